@@ -0,0 +1,22 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// The urltag command checks the struct tags used by the go-querystring query
+// package.  It may be run directly on packages,
+//
+//	urltag ./...
+//
+// or by go vet:
+//
+//	go vet -vettool=$(which urltag) ./...
+package main
+
+import (
+	"github.com/google/go-querystring/analysis/urltag"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(urltag.Analyzer)
+}
@@ -0,0 +1,65 @@
+package a
+
+import "time"
+
+type Options struct {
+	Query  string   `url:"q,omitmepty"`                  // want `unknown option "omitmepty" in url tag`
+	IDs    []int    `url:"ids,coma"`                     // want `unknown option "coma" in url tag`
+	Trail  string   `url:"trail,"`                       // want `empty option in url tag`
+	Tags   []string `url:"tags,comma,brackets"`          // want `conflicting options comma and brackets in url tag`
+	Styles []string `url:"styles,form,pipeDelimited"`    // want `conflicting options form and pipeDelimited in url tag`
+	Mixed  []string `url:"mixed,space,form"`             // want `option space overrides style form in url tag`
+	Both   []string `url:"both,form,explode,noexplode"`  // want `conflicting options explode and noexplode in url tag`
+	Day    string   `layout:"2006-01-02"`                // want `layout tag on field of type string, which is not time.Time`
+	Bits   []bool   `url:"bits,comma" del:"!"`           // want `del tag is ignored with option comma`
+	Colors []string `url:"colors,pipeDelimited" del:"!"` // want `del tag is ignored with style pipeDelimited`
+	Page   int      `url:"page"`
+	Offset int      `url:"page"` // want `field Offset has the same URL parameter name "page" as field Page`
+	Limit  int
+	Max    int    `url:"Limit"`       // want `field Max has the same URL parameter name "Limit" as field Limit`
+	Order  Sort   `url:"page,del=::"` // want `field Order has the same URL parameter name "page" as field Page`
+	ID     string `path:"id,sapce"`   // want `unknown option "sapce" in path tag`
+	Token  string `header:"X-Token,omitempty"`
+	Start  int    `url:"page" path:"-"` // want `field Start has the same URL parameter name "page" as field Page`
+	Hidden string `url:"-"`
+	Skip   string `url:"-" del:","`
+}
+
+type Valid struct {
+	Query  string      `url:"q,omitempty"`
+	Since  time.Time   `url:"since,unix"`
+	Until  *time.Time  `layout:"2006-01-02"`
+	Days   []time.Time `layout:"2006-01-02"`
+	Any    interface{} `layout:"2006-01-02"`
+	Bits   []bool      `url:"bits,int" del:"!"`
+	Colors []string    `url:"color,pipeDelimited,explode"`
+	Owner  string      `path:"owner"`
+	Repo   string      `path:"owner"`
+	Page   int         `url:"page"`
+	A, B   string
+	Nested struct{ Page int } `url:"nested"`
+	Embedded
+	*Other
+	Sort  Sort  `url:",inline"`
+	Sort2 *Sort `url:",inline"`
+	Sort3 Sort  `url:",prefix=x_,suffix=_y"`
+	Sort4 Sort  `url:",prefix=z_,omitempty"`   // want `option omitempty is ignored on flattened struct field`
+	Size  int   `url:"size,prefix"`            // want `unknown option "prefix" in url tag`
+	IDs   []int `url:"ids,brackets,indexed=1"` // want `conflicting options brackets and indexed in url tag`
+	Paths []int `url:"paths,del=::,escape"`
+	Notes []int `url:"notes,comma,escape=percent"`
+	Marks []int `url:"marks,comma,escape=html"` // want `unknown policy "html" for option escape in url tag`
+	Refs  []int `url:"refs,del=::" del:"|"`     // want `del tag is ignored with option del`
+}
+
+type Sort struct {
+	Order string `url:"order"`
+}
+
+type Embedded struct {
+	Page int `url:"page"`
+}
+
+type Other struct {
+	Query string `url:"q"`
+}
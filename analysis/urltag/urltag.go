@@ -0,0 +1,320 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package urltag defines an Analyzer that checks the struct tags read by the
+// github.com/google/go-querystring/query package.
+package urltag
+
+import (
+	"go/ast"
+	"go/types"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+const doc = `check struct tags used by the go-querystring query package
+
+The query package silently ignores options it does not recognize in "url",
+"path" and "header" struct tags, and picks one of several conflicting
+options.  This analyzer reports:
+
+  - unknown or empty options, such as "omitmepty" or "coma", and unknown
+    policies for the "escape" option
+  - more than one slice encoding option, such as "comma,brackets" or
+    "brackets,indexed=1"
+  - more than one OpenAPI style, or both "explode" and "noexplode"
+  - "layout" tags on fields that are not time.Time values
+  - "del" tags that are overridden by a slice encoding option or style
+  - the "omitempty" option on struct fields flattened by "inline", "prefix"
+    or "suffix", which has no effect
+  - fields of the same struct with the same URL parameter name`
+
+// Analyzer checks the struct tags used by the query package.
+var Analyzer = &analysis.Analyzer{
+	Name:     "urltag",
+	Doc:      doc,
+	URL:      "https://pkg.go.dev/github.com/google/go-querystring/analysis/urltag",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+// tagKeys are the struct tag keys whose options are interpreted by the query
+// package.
+var tagKeys = []string{"url", "path", "header"}
+
+// knownOptions are the options recognized in tagKeys tags.
+var knownOptions = map[string]bool{
+	"omitempty":      true,
+	"int":            true,
+	"unix":           true,
+	"unixmilli":      true,
+	"unixnano":       true,
+	"httpdate":       true,
+	"comma":          true,
+	"space":          true,
+	"semicolon":      true,
+	"brackets":       true,
+	"numbered":       true,
+	"form":           true,
+	"spaceDelimited": true,
+	"pipeDelimited":  true,
+	"deepObject":     true,
+	"explode":        true,
+	"noexplode":      true,
+	"inline":         true,
+	"omitemptyelem":  true,
+	"keepempty":      true,
+	"indexed":        true,
+	"pipe":           true,
+	"escape":         true,
+}
+
+// valueOptions are the options of the form "name=value".
+var valueOptions = []string{"prefix", "suffix", "indexed", "del", "escape"}
+
+// escapePolicies are the values of the "escape" option.
+var escapePolicies = []string{"backslash", "percent", "quote", "error"}
+
+// sliceOptions are the mutually exclusive options controlling how slices are
+// encoded, other than OpenAPI styles.
+var sliceOptions = []string{"comma", "space", "semicolon", "pipe", "del", "brackets", "numbered", "indexed"}
+
+// styleOptions are the mutually exclusive OpenAPI style options.
+var styleOptions = []string{"form", "spaceDelimited", "pipeDelimited", "deepObject"}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	nodeFilter := []ast.Node{(*ast.StructType)(nil)}
+	inspect.Preorder(nodeFilter, func(n ast.Node) {
+		checkStruct(pass, n.(*ast.StructType))
+	})
+	return nil, nil
+}
+
+// checkStruct reports problems with the tags of the fields of st.
+func checkStruct(pass *analysis.Pass, st *ast.StructType) {
+	seen := map[string]string{} // URL parameter name to field name
+
+	for _, f := range st.Fields.List {
+		var tag reflect.StructTag
+		if f.Tag != nil {
+			s, err := strconv.Unquote(f.Tag.Value)
+			if err != nil {
+				continue
+			}
+			tag = reflect.StructTag(s)
+		}
+		typ := pass.TypesInfo.TypeOf(f.Type)
+
+		for _, key := range tagKeys {
+			if value, ok := tag.Lookup(key); ok {
+				checkOptions(pass, f, key, value, tag)
+			}
+		}
+		if _, ok := tag.Lookup("layout"); ok && !isTimeField(typ) {
+			pass.Reportf(f.Tag.Pos(), "layout tag on field of type %s, which is not time.Time", typ)
+		}
+
+		// duplicate URL parameter names
+		value := tag.Get("url")
+		if value == "-" || hasPlacementTag(tag) {
+			continue
+		}
+		name, opts := strings.Split(value, ",")[0], strings.Split(value, ",")[1:]
+		if isStruct(typ) && flattens(opts) {
+			// inlined or prefixed struct fields are encoded in place
+			if contains(opts, "omitempty") {
+				pass.Reportf(f.Tag.Pos(), "option omitempty is ignored on flattened struct field")
+			}
+			continue
+		}
+		for _, fieldName := range fieldNames(f) {
+			if !ast.IsExported(fieldName) {
+				continue
+			}
+			n := name
+			if n == "" {
+				if len(f.Names) == 0 && isStruct(typ) {
+					// embedded struct fields are encoded in place
+					continue
+				}
+				n = fieldName
+			}
+			if prev, ok := seen[n]; ok {
+				pass.Reportf(f.Pos(), "field %s has the same URL parameter name %q as field %s", fieldName, n, prev)
+				continue
+			}
+			seen[n] = fieldName
+		}
+	}
+}
+
+// checkOptions reports problems with the options of the tag value for key.
+func checkOptions(pass *analysis.Pass, f *ast.Field, key, value string, tag reflect.StructTag) {
+	if value == "-" {
+		return
+	}
+	opts := strings.Split(value, ",")[1:]
+
+	has := map[string]bool{}
+	for _, o := range opts {
+		switch {
+		case o == "":
+			pass.Reportf(f.Tag.Pos(), "empty option in %s tag", key)
+		case isValueOption(o):
+			// record the option's name, without its value
+			i := strings.Index(o, "=")
+			if o[:i] == "escape" && !contains(escapePolicies, o[i+1:]) {
+				pass.Reportf(f.Tag.Pos(), "unknown policy %q for option escape in %s tag", o[i+1:], key)
+			}
+			o = o[:i]
+		case !knownOptions[o]:
+			pass.Reportf(f.Tag.Pos(), "unknown option %q in %s tag", o, key)
+		}
+		has[o] = true
+	}
+
+	slice := present(has, sliceOptions)
+	if len(slice) > 1 {
+		pass.Reportf(f.Tag.Pos(), "conflicting options %s in %s tag", strings.Join(slice, " and "), key)
+	}
+	styles := present(has, styleOptions)
+	if len(styles) > 1 {
+		pass.Reportf(f.Tag.Pos(), "conflicting options %s in %s tag", strings.Join(styles, " and "), key)
+	}
+	if len(slice) > 0 && len(styles) > 0 {
+		pass.Reportf(f.Tag.Pos(), "option %s overrides style %s in %s tag", slice[0], styles[0], key)
+	}
+	if has["explode"] && has["noexplode"] {
+		pass.Reportf(f.Tag.Pos(), "conflicting options explode and noexplode in %s tag", key)
+	}
+
+	if _, ok := tag.Lookup("del"); ok && key == "url" {
+		if len(slice) > 0 {
+			pass.Reportf(f.Tag.Pos(), "del tag is ignored with option %s", slice[0])
+		} else if len(styles) > 0 {
+			pass.Reportf(f.Tag.Pos(), "del tag is ignored with style %s", styles[0])
+		}
+	}
+}
+
+// isValueOption reports whether o has the form "name=value" for one of the
+// valueOptions.
+func isValueOption(o string) bool {
+	for _, name := range valueOptions {
+		if strings.HasPrefix(o, name+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+// contains reports whether list contains s.
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// flattens reports whether opts cause a struct field to be flattened into
+// its enclosing struct.
+func flattens(opts []string) bool {
+	for _, o := range opts {
+		if o == "inline" || strings.HasPrefix(o, "prefix=") || strings.HasPrefix(o, "suffix=") {
+			return true
+		}
+	}
+	return false
+}
+
+// present returns the options of list that are in has, in list order.
+func present(has map[string]bool, list []string) []string {
+	var found []string
+	for _, o := range list {
+		if has[o] {
+			found = append(found, o)
+		}
+	}
+	return found
+}
+
+// fieldNames returns the Go names of the fields declared by f.
+func fieldNames(f *ast.Field) []string {
+	if len(f.Names) == 0 {
+		// embedded field, named after its type
+		t := f.Type
+		if star, ok := t.(*ast.StarExpr); ok {
+			t = star.X
+		}
+		switch t := t.(type) {
+		case *ast.Ident:
+			return []string{t.Name}
+		case *ast.SelectorExpr:
+			return []string{t.Sel.Name}
+		}
+		return nil
+	}
+
+	names := make([]string, len(f.Names))
+	for i, n := range f.Names {
+		names[i] = n.Name
+	}
+	return names
+}
+
+// hasPlacementTag reports whether tag places the field outside the query
+// string, so that it has no URL parameter name.  A placement tag of "-" does
+// not.
+func hasPlacementTag(tag reflect.StructTag) bool {
+	for _, key := range tagKeys[1:] {
+		if v, ok := tag.Lookup(key); ok && v != "-" {
+			return true
+		}
+	}
+	return false
+}
+
+// isTimeField reports whether t is time.Time, or a pointer, slice or array
+// of time.Time values, for which the layout tag is used.  Interface types are
+// accepted, as they may hold time.Time values.
+func isTimeField(t types.Type) bool {
+	for {
+		if n, ok := t.(*types.Named); ok {
+			obj := n.Obj()
+			if obj.Pkg() != nil && obj.Pkg().Path() == "time" && obj.Name() == "Time" {
+				return true
+			}
+		}
+		switch u := t.Underlying().(type) {
+		case *types.Pointer:
+			t = u.Elem()
+		case *types.Slice:
+			t = u.Elem()
+		case *types.Array:
+			t = u.Elem()
+		case *types.Interface:
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+// isStruct reports whether t is a struct type or a pointer to one.
+func isStruct(t types.Type) bool {
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	_, ok := t.Underlying().(*types.Struct)
+	return ok
+}
@@ -0,0 +1,375 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"go/types"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// field represents a single struct field that is encoded as a URL parameter.
+// It mirrors the field type of the query package, which querygen must agree
+// with exactly.
+type field struct {
+	name  string       // URL parameter name, without any scope
+	tag   bool         // whether name was given in the struct tag
+	index []int        // index sequence for reaching the field from its struct
+	path  []*types.Var // fields followed to reach the field, ending with it
+	opts  tagOptions
+	stag  reflect.StructTag
+}
+
+// placementTags are the struct tag keys that place a field somewhere other
+// than the query string, as in the query package.
+var placementTags = []string{"path", "header"}
+
+// typeFields returns the fields of the struct type t that query.Values
+// encodes, in the order it encodes them.  Embedded structs and fields with
+// the same name are handled exactly as in the query package, including not
+// following a struct from within itself.
+func typeFields(t *types.Named) []field {
+	type queued struct {
+		typ            types.Type
+		index          []int
+		path           []*types.Var
+		prefix, suffix string       // added to the names of the struct's fields
+		parents        []types.Type // structs followed to reach typ
+	}
+	type visit struct {
+		typ            types.Type
+		prefix, suffix string
+	}
+
+	var fields []field
+	var current []queued
+	next := []queued{{typ: t}}
+
+	// types already expanded at a shallower depth with the same names
+	visited := map[visit]bool{}
+
+	for len(next) > 0 {
+		current, next = next, nil
+
+		for _, q := range current {
+			if visited[visit{q.typ, q.prefix, q.suffix}] {
+				continue
+			}
+
+			st := q.typ.Underlying().(*types.Struct)
+			for i := 0; i < st.NumFields(); i++ {
+				v := st.Field(i)
+				if v.Embedded() {
+					if !v.Exported() && !isStruct(deref(v.Type())) {
+						// ignore embedded fields of unexported non-struct types
+						continue
+					}
+				} else if !v.Exported() {
+					continue
+				}
+
+				stag := reflect.StructTag(st.Tag(i))
+				tag := stag.Get("url")
+				if tag == "-" || hasPlacementTag(stag) {
+					continue
+				}
+				name, opts := parseTag(tag)
+
+				index := make([]int, len(q.index)+1)
+				copy(index, q.index)
+				index[len(q.index)] = i
+				path := make([]*types.Var, len(q.path)+1)
+				copy(path, q.path)
+				path[len(q.path)] = v
+
+				prefix, hasPrefix := opts.Get("prefix")
+				suffix, hasSuffix := opts.Get("suffix")
+				if name == "" && v.Embedded() || opts.Contains("inline") || hasPrefix || hasSuffix {
+					switch ft := deref(v.Type()); {
+					case !isStruct(ft):
+					case types.Identical(ft, q.typ) || containsType(q.parents, ft):
+						if v.Embedded() && name == "" {
+							// recursive embedded struct, already followed
+							continue
+						}
+					default:
+						// save embedded or inlined struct for processing at
+						// the next depth
+						parents := append(q.parents[:len(q.parents):len(q.parents)], q.typ)
+						next = append(next, queued{ft, index, path, q.prefix + prefix, suffix + q.suffix, parents})
+						continue
+					}
+				}
+
+				f := field{
+					name:  name,
+					tag:   name != "",
+					index: index,
+					path:  path,
+					opts:  opts,
+					stag:  stag,
+				}
+				if f.name == "" {
+					f.name = v.Name()
+				}
+				f.name = q.prefix + f.name + q.suffix
+				fields = append(fields, f)
+			}
+		}
+
+		for _, q := range current {
+			visited[visit{q.typ, q.prefix, q.suffix}] = true
+		}
+	}
+
+	sort.Slice(fields, func(i, j int) bool {
+		x := fields
+		if x[i].name != x[j].name {
+			return x[i].name < x[j].name
+		}
+		if len(x[i].index) != len(x[j].index) {
+			return len(x[i].index) < len(x[j].index)
+		}
+		if x[i].tag != x[j].tag {
+			return x[i].tag
+		}
+		return indexLess(x[i].index, x[j].index)
+	})
+
+	var list []field
+	for i, n := 0, 0; i < len(fields); i += n {
+		for n = 1; i+n < len(fields); n++ {
+			if fields[i+n].name != fields[i].name {
+				break
+			}
+		}
+		list = append(list, dominantFields(fields[i:i+n])...)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return indexLess(list[i].index, list[j].index)
+	})
+
+	return list
+}
+
+// containsType reports whether list contains a type identical to t.
+func containsType(list []types.Type, t types.Type) bool {
+	for _, x := range list {
+		if types.Identical(x, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPlacementTag reports whether tag has one of the placementTags, with a
+// value other than "-".
+func hasPlacementTag(tag reflect.StructTag) bool {
+	for _, key := range placementTags {
+		if v, ok := tag.Lookup(key); ok && v != "-" {
+			return true
+		}
+	}
+	return false
+}
+
+// dominantFields returns the fields that should be encoded out of a set of
+// fields sharing the same name, sorted as in typeFields.  The result is empty
+// if the name is ambiguous.
+func dominantFields(fields []field) []field {
+	depth, tag := len(fields[0].index), fields[0].tag
+
+	n := 1
+	for n < len(fields) && len(fields[n].index) == depth && fields[n].tag == tag {
+		n++
+	}
+
+	// ties are only allowed between fields of the same struct
+	parent := fields[0].index[:depth-1]
+	for _, f := range fields[1:n] {
+		if !indexEqual(f.index[:depth-1], parent) {
+			return nil
+		}
+	}
+
+	// tagged fields only hide the untagged fields of other structs
+	dominant := fields[:n:n]
+	for _, f := range fields[n:] {
+		if len(f.index) == depth && indexEqual(f.index[:depth-1], parent) {
+			dominant = append(dominant, f)
+		}
+	}
+	return dominant
+}
+
+// indexLess reports whether index sequence a sorts before b.
+func indexLess(a, b []int) bool {
+	for k, x := range a {
+		if k >= len(b) {
+			return false
+		}
+		if x != b[k] {
+			return x < b[k]
+		}
+	}
+	return len(a) < len(b)
+}
+
+// indexEqual reports whether index sequences a and b are the same.
+func indexEqual(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k := range a {
+		if a[k] != b[k] {
+			return false
+		}
+	}
+	return true
+}
+
+// deref returns the type pointed to by t if it is a pointer, or else t.
+func deref(t types.Type) types.Type {
+	if p, ok := t.(*types.Pointer); ok {
+		return p.Elem()
+	}
+	return t
+}
+
+// isStruct reports whether t is a struct type.
+func isStruct(t types.Type) bool {
+	_, ok := t.Underlying().(*types.Struct)
+	return ok
+}
+
+// tagOptions is the string following a comma in a struct field's "url" tag, or
+// the empty string. It does not include the leading comma.
+type tagOptions []string
+
+// parseTag splits a struct field's url tag into its name and comma-separated
+// options.
+func parseTag(tag string) (string, tagOptions) {
+	s := strings.Split(tag, ",")
+	return s[0], s[1:]
+}
+
+// Contains checks whether the tagOptions contains the specified option.
+func (o tagOptions) Contains(option string) bool {
+	for _, s := range o {
+		if s == option {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns the value of the option of the form "name=value", and whether
+// it is present.
+func (o tagOptions) Get(name string) (string, bool) {
+	for _, s := range o {
+		if strings.HasPrefix(s, name+"=") {
+			return s[len(name)+1:], true
+		}
+	}
+	return "", false
+}
+
+// sliceDelimiter returns the delimiter query.Values uses to join the elements
+// of a slice or array field into a single value, or "" if each element is
+// encoded as a separate value.
+func sliceDelimiter(opts tagOptions, tag reflect.StructTag) string {
+	switch {
+	case opts.Contains("comma"):
+		return ","
+	case opts.Contains("space"):
+		return " "
+	case opts.Contains("semicolon"):
+		return ";"
+	case opts.Contains("pipe"):
+		return "|"
+	case opts.Contains("brackets"):
+		return ""
+	}
+	if del, ok := opts.Get("del"); ok && del != "" {
+		return del
+	}
+	if _, ok := indexStart(opts); ok {
+		return ""
+	}
+	if style, explode := openAPIStyle(opts); style != "" {
+		if explode {
+			return ""
+		}
+		return styleDelimiters[style]
+	}
+	return tag.Get("del")
+}
+
+// escapePolicy returns the policy given by the "escape" option in opts, or ""
+// if it is not present, as in the query package.
+func escapePolicy(opts tagOptions) string {
+	if opts.Contains("escape") {
+		return "backslash"
+	}
+	s, ok := opts.Get("escape")
+	if !ok {
+		return ""
+	}
+	switch s {
+	case "backslash", "percent", "quote", "error":
+		return s
+	}
+	return "backslash"
+}
+
+// indexStart returns the index of the first element of a slice or array
+// encoded with the "indexed" option, and whether the option is present, as
+// in the query package.
+func indexStart(opts tagOptions) (int, bool) {
+	if opts.Contains("indexed") {
+		return 0, true
+	}
+	s, ok := opts.Get("indexed")
+	if !ok {
+		return 0, false
+	}
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 {
+		return n, true
+	}
+	return 0, true
+}
+
+// styleDelimiters maps OpenAPI parameter styles to the delimiter used when
+// they are not exploded.
+var styleDelimiters = map[string]string{
+	"form":           ",",
+	"spaceDelimited": " ",
+	"pipeDelimited":  "|",
+}
+
+// openAPIStyle returns the OpenAPI parameter style named in opts, if any, and
+// whether the parameter is exploded.
+func openAPIStyle(opts tagOptions) (style string, explode bool) {
+	for _, s := range []string{"form", "spaceDelimited", "pipeDelimited", "deepObject"} {
+		if opts.Contains(s) {
+			style = s
+			break
+		}
+	}
+	if style == "" {
+		return "", false
+	}
+
+	explode = style == "form" || style == "deepObject"
+	if opts.Contains("explode") {
+		explode = true
+	} else if opts.Contains("noexplode") {
+		explode = false
+	}
+	return style, explode
+}
@@ -0,0 +1,652 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const queryPath = "github.com/google/go-querystring/query"
+
+// generator generates code for the types of a single package.
+type generator struct {
+	pkg     *types.Package
+	encoder *types.Interface // query.Encoder
+
+	// types being given EncodeValues methods, which they do not have yet
+	generating map[*types.Named]bool
+
+	buf     bytes.Buffer    // body of the file being generated
+	imports map[string]bool // imports of the file being generated
+}
+
+// newGenerator parses and type-checks the package in dir, ignoring the
+// previously generated files named in exclude.
+func newGenerator(dir string, exclude []string) (*generator, error) {
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range bp.GoFiles {
+		if contains(exclude, name) {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+
+	imp := importer.ForCompiler(fset, "source", nil)
+	conf := types.Config{Importer: imp}
+	pkg, err := conf.Check(bp.ImportPath, fset, files, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// query.Encoder is constructed rather than imported, so that the package
+	// need not already import the query package.
+	urlPkg, err := imp.Import("net/url")
+	if err != nil {
+		return nil, err
+	}
+	values := urlPkg.Scope().Lookup("Values").Type()
+	params := types.NewTuple(
+		types.NewVar(token.NoPos, nil, "key", types.Typ[types.String]),
+		types.NewVar(token.NoPos, nil, "v", types.NewPointer(values)),
+	)
+	results := types.NewTuple(types.NewVar(token.NoPos, nil, "", types.Universe.Lookup("error").Type()))
+	method := types.NewFunc(token.NoPos, nil, "EncodeValues", types.NewSignature(nil, params, results, false))
+
+	return &generator{
+		pkg:     pkg,
+		encoder: types.NewInterfaceType([]*types.Func{method}, nil).Complete(),
+	}, nil
+}
+
+// generate returns the source of a file declaring EncodeValues methods for
+// the named types.  header is the command line recorded in the file.
+func (g *generator) generate(header string, names []string) ([]byte, error) {
+	g.buf.Reset()
+	g.imports = map[string]bool{"net/url": true}
+
+	var list []*types.Named
+	g.generating = map[*types.Named]bool{}
+	for _, name := range names {
+		t, err := g.lookup(name)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, t)
+		g.generating[t] = true
+	}
+
+	for _, t := range list {
+		if e := g.embeddedEncoder(t, map[types.Type]bool{}); e != nil {
+			return nil, fmt.Errorf("type %s would have an EncodeValues method promoted from embedded type %s", t.Obj().Name(), e.Obj().Name())
+		}
+		if err := g.generateType(t); err != nil {
+			return nil, err
+		}
+	}
+
+	return g.format(header)
+}
+
+// lookup returns the struct type with the given name, checking that an
+// EncodeValues method may be generated for it.
+func (g *generator) lookup(name string) (*types.Named, error) {
+	tn, ok := g.pkg.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("type %s not found in package %s", name, g.pkg.Name())
+	}
+	t, ok := tn.Type().(*types.Named)
+	if !ok || !isStruct(t) {
+		return nil, fmt.Errorf("%s is not a struct type", name)
+	}
+
+	obj, index, _ := types.LookupFieldOrMethod(types.NewPointer(t), false, g.pkg, "EncodeValues")
+	if _, ok := obj.(*types.Func); ok {
+		if len(index) > 1 {
+			return nil, fmt.Errorf("type %s has an EncodeValues method promoted from an embedded field", name)
+		}
+		return nil, fmt.Errorf("type %s already has an EncodeValues method", name)
+	}
+
+	return t, nil
+}
+
+// embeddedEncoder returns the type being generated, if any, that is embedded
+// in the struct type t, directly or through other embedded structs.
+func (g *generator) embeddedEncoder(t types.Type, visited map[types.Type]bool) *types.Named {
+	if visited[t] {
+		return nil
+	}
+	visited[t] = true
+
+	st := t.Underlying().(*types.Struct)
+	for i := 0; i < st.NumFields(); i++ {
+		v := st.Field(i)
+		if !v.Embedded() || !isStruct(deref(v.Type())) {
+			continue
+		}
+		ft := deref(v.Type())
+		if n, ok := ft.(*types.Named); ok && g.generating[n] {
+			return n
+		}
+		if e := g.embeddedEncoder(ft, visited); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+// generateType writes the EncodeValues method for t.
+func (g *generator) generateType(t *types.Named) error {
+	var body bytes.Buffer
+	usesKey := false
+	guard := ""
+	for _, f := range typeFields(t) {
+		code, fieldGuard, inline, err := g.fieldCode(f)
+		if err != nil {
+			return fmt.Errorf("%s: %v", t.Obj().Name(), err)
+		}
+		usesKey = usesKey || inline
+
+		// consecutive fields of the same embedded struct pointer share
+		// a nil check
+		if fieldGuard != guard {
+			if guard != "" {
+				body.WriteString("}\n")
+			}
+			if fieldGuard != "" {
+				fmt.Fprintf(&body, "if %s {\n", fieldGuard)
+			}
+			guard = fieldGuard
+		}
+		body.WriteString(code)
+	}
+	if guard != "" {
+		body.WriteString("}\n")
+	}
+
+	fmt.Fprintf(&g.buf, "// EncodeValues implements query.Encoder, encoding the fields of x as\n")
+	fmt.Fprintf(&g.buf, "// query.Values does.\n")
+	fmt.Fprintf(&g.buf, "func (x %s) EncodeValues(scope string, v *url.Values) error {\n", t.Obj().Name())
+	if usesKey {
+		fmt.Fprintf(&g.buf, "prefix, suffix := \"\", \"\"\n")
+		fmt.Fprintf(&g.buf, "if scope != \"\" {\nprefix, suffix = scope+\"[\", \"]\"\n}\n\n")
+	}
+	g.buf.Write(body.Bytes())
+	fmt.Fprintf(&g.buf, "return nil\n}\n\n")
+
+	fmt.Fprintf(&g.buf, "// QuerygenEncoder marks EncodeValues as generated, so that query.Values\n")
+	fmt.Fprintf(&g.buf, "// calls it to encode x.\n")
+	fmt.Fprintf(&g.buf, "func (%s) QuerygenEncoder() {}\n\n", t.Obj().Name())
+	return nil
+}
+
+// fieldCode returns the code encoding the field f of x, the condition under
+// which it is encoded, and whether the field is encoded directly rather than
+// by query.EncodeField.
+func (g *generator) fieldCode(f field) (code, guard string, inline bool, err error) {
+	expr := "x"
+	var guards []string
+	for i, v := range f.path {
+		if !v.Exported() && v.Pkg() != g.pkg {
+			return "", "", false, fmt.Errorf("field %s of %s is not accessible", v.Name(), expr)
+		}
+		expr += "." + v.Name()
+		if _, ok := v.Type().(*types.Pointer); ok && i < len(f.path)-1 {
+			// fields of nil embedded struct pointers are skipped
+			guards = append(guards, expr+" != nil")
+		}
+	}
+
+	code, inline = g.inlineCode(f, expr)
+	if !inline {
+		g.imports[queryPath] = true
+		code = fmt.Sprintf("if err := query.EncodeField(v, scope, %s, &%s, %s); err != nil {\nreturn err\n}\n",
+			strconv.Quote(f.name), expr, quote(string(f.stag)))
+	}
+	return code, strings.Join(guards, " && "), inline, nil
+}
+
+// inlineCode returns the code encoding the field f, reached by expr, without
+// reflection.  It reports false if the field's type is not supported.
+func (g *generator) inlineCode(f field, expr string) (string, bool) {
+	t := f.path[len(f.path)-1].Type()
+	key := "prefix + " + strconv.Quote(f.name) + " + suffix"
+	add := func(s string) string {
+		return "v.Add(" + key + ", " + s + ")"
+	}
+
+	cond := ""
+	if f.opts.Contains("omitempty") {
+		var ok bool
+		if cond, ok = g.nonEmpty(expr, t); !ok {
+			return "", false
+		}
+	}
+
+	var code string
+	switch u := t.Underlying().(type) {
+	case *types.Interface:
+		return "", false
+	case *types.Pointer:
+		if g.isEncoder(t) || !g.isScalar(u.Elem()) {
+			return "", false
+		}
+		code = g.scalarCode("*"+expr, u.Elem(), f, add)
+		if cond == "" {
+			// nil pointers are encoded as empty strings
+			code = fmt.Sprintf("if %s != nil {\n%s} else {\n%s\n}\n", expr, code, add(`""`))
+		}
+	default:
+		switch {
+		case g.isEncoder(t):
+			if style, _ := openAPIStyle(f.opts); style != "" && g.isGenerated(t) {
+				// generated encoders are bypassed for OpenAPI styles
+				return "", false
+			}
+			code = fmt.Sprintf("if err := %s.EncodeValues(%s, v); err != nil {\nreturn err\n}\n", expr, key)
+		case g.isScalar(t):
+			code = g.scalarCode(expr, t, f, add)
+		case isSequence(t) && g.isScalar(elem(t)):
+			if f.opts.Contains("keepempty") && f.opts.Contains("omitemptyelem") {
+				// whether any elements remain is left to query.EncodeField
+				return "", false
+			}
+			if p := escapePolicy(f.opts); p == "quote" || p == "error" {
+				// elements are checked for the delimiter by query.EncodeField
+				return "", false
+			}
+			code = g.sequenceCode(expr, elem(t), f, key) + g.emptySequenceCode(expr, t, f, key)
+		default:
+			return "", false
+		}
+	}
+
+	if cond != "" {
+		code = fmt.Sprintf("if %s {\n%s}\n", cond, code)
+	}
+	return code, true
+}
+
+// sequenceCode returns the code encoding the elements of the slice or array
+// reached by expr, whose elements have type elem.
+func (g *generator) sequenceCode(expr string, elem types.Type, f field, key string) string {
+	if f.opts.Contains("omitemptyelem") {
+		return g.nonEmptySequenceCode(expr, elem, f, key)
+	}
+
+	if del := sliceDelimiter(f.opts, f.stag); del != "" {
+		decl, write := g.delimitedWriter(del, f)
+		return fmt.Sprintf("if len(%s) > 0 {\n%sfor i, e := range %s {\nif i > 0 {\nb.WriteString(%q)\n}\n%s}\nv.Add(%s, b.String())\n}\n",
+			expr, decl, expr, del, g.scalarCode("e", elem, f, write), key)
+	}
+
+	if f.opts.Contains("brackets") {
+		key += ` + "[]"`
+	}
+	index := "_"
+	if k := g.elemKey(key, f, "i"); k != "" {
+		key, index = k, "i"
+	}
+	add := func(s string) string {
+		return "v.Add(" + key + ", " + s + ")"
+	}
+	return fmt.Sprintf("for %s, e := range %s {\n%s}\n", index, expr, g.scalarCode("e", elem, f, add))
+}
+
+// delimitedWriter returns the declarations needed to join the elements of a
+// sequence with the delimiter del into the strings.Builder b, and a function
+// returning the code writing an element's string form to b, escaping the
+// delimiter if the field has the "escape" option with the "backslash" or
+// "percent" policy.
+func (g *generator) delimitedWriter(del string, f field) (decl string, write func(string) string) {
+	g.imports["strings"] = true
+	decl = "var b strings.Builder\n"
+	switch escapePolicy(f.opts) {
+	case "backslash":
+		decl += fmt.Sprintf("r := strings.NewReplacer(%q, %q, %q, %q)\n", `\`, `\\`, del, `\`+del)
+	case "percent":
+		decl += fmt.Sprintf("r := strings.NewReplacer(%q, %q, %q, %q)\n", "%", "%25", del, percentEncode(del))
+	default:
+		return decl, func(s string) string {
+			return "b.WriteString(" + s + ")"
+		}
+	}
+	return decl, func(s string) string {
+		return "r.WriteString(&b, " + s + ")"
+	}
+}
+
+// percentEncode returns s with every byte percent-encoded.
+func percentEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		fmt.Fprintf(&b, "%%%02X", s[i])
+	}
+	return b.String()
+}
+
+// elemKey returns the expression for the URL parameter name of an element of
+// a sequence with the "numbered" or "indexed" option, given its name key and
+// the int variable v counting its elements, or "" for other sequences.
+func (g *generator) elemKey(key string, f field, v string) string {
+	if f.opts.Contains("numbered") {
+		g.imports["strconv"] = true
+		return key + " + strconv.Itoa(" + v + ")"
+	}
+	if start, ok := indexStart(f.opts); ok {
+		g.imports["strconv"] = true
+		return key + ` + "[" + strconv.Itoa(` + offset(v, start) + `) + "]"`
+	}
+	return ""
+}
+
+// emptySequenceCode returns the code encoding the slice or array of type t
+// reached by expr as a single empty value if it is empty but not nil, for
+// fields with the "keepempty" option.
+func (g *generator) emptySequenceCode(expr string, t types.Type, f field, key string) string {
+	if !f.opts.Contains("keepempty") {
+		return ""
+	}
+	if sliceDelimiter(f.opts, f.stag) == "" && f.opts.Contains("brackets") {
+		key += ` + "[]"`
+	}
+	add := "v.Add(" + key + `, "")` + "\n"
+
+	if a, ok := t.Underlying().(*types.Array); ok {
+		if a.Len() == 0 {
+			return add
+		}
+		return ""
+	}
+	return fmt.Sprintf("if %s != nil && len(%s) == 0 {\n%s}\n", expr, expr, add)
+}
+
+// nonEmptySequenceCode returns the code encoding the non-empty elements of
+// the slice or array reached by expr, whose elements have type elem, for
+// fields with the "omitemptyelem" option.  Elements are always scalars, whose
+// emptiness can be expressed directly.
+func (g *generator) nonEmptySequenceCode(expr string, elem types.Type, f field, key string) string {
+	cond, _ := g.nonEmpty("e", elem)
+
+	if del := sliceDelimiter(f.opts, f.stag); del != "" {
+		decl, write := g.delimitedWriter(del, f)
+		return fmt.Sprintf("if len(%s) > 0 {\n%sn := 0\nfor _, e := range %s {\nif %s {\nif n > 0 {\nb.WriteString(%q)\n}\nn++\n%s}\n}\nif n > 0 {\nv.Add(%s, b.String())\n}\n}\n",
+			expr, decl, expr, cond, del, g.scalarCode("e", elem, f, write), key)
+	}
+
+	if f.opts.Contains("brackets") {
+		key += ` + "[]"`
+	}
+	if k := g.elemKey(key, f, "n"); k != "" {
+		add := func(s string) string {
+			return "v.Add(" + k + ", " + s + ")"
+		}
+		return fmt.Sprintf("if len(%s) > 0 {\nn := 0\nfor _, e := range %s {\nif %s {\n%sn++\n}\n}\n}\n",
+			expr, expr, cond, g.scalarCode("e", elem, f, add))
+	}
+	add := func(s string) string {
+		return "v.Add(" + key + ", " + s + ")"
+	}
+	return fmt.Sprintf("for _, e := range %s {\nif %s {\n%s}\n}\n", expr, cond, g.scalarCode("e", elem, f, add))
+}
+
+// offset returns the expression adding start to the int variable v.
+func offset(v string, start int) string {
+	if start == 0 {
+		return v
+	}
+	return v + "+" + strconv.Itoa(start)
+}
+
+// scalarCode returns the code passing the string form of the value of type t
+// reached by expr to sink, as query.Values formats it.
+func (g *generator) scalarCode(expr string, t types.Type, f field, sink func(string) string) string {
+	if isTime(t) {
+		if strings.HasPrefix(expr, "*") {
+			expr = "(" + expr + ")"
+		}
+		var s string
+		switch layout := f.stag.Get("layout"); {
+		case f.opts.Contains("unix"):
+			g.imports["strconv"] = true
+			s = "strconv.FormatInt(" + expr + ".Unix(), 10)"
+		case f.opts.Contains("unixmilli"):
+			g.imports["strconv"] = true
+			s = "strconv.FormatInt(" + expr + ".UnixNano()/1e6, 10)"
+		case f.opts.Contains("unixnano"):
+			g.imports["strconv"] = true
+			s = "strconv.FormatInt(" + expr + ".UnixNano(), 10)"
+		case f.opts.Contains("httpdate"):
+			g.imports["net/http"] = true
+			s = expr + ".UTC().Format(http.TimeFormat)"
+		case layout != "":
+			s = expr + ".Format(" + strconv.Quote(layout) + ")"
+		default:
+			g.imports["time"] = true
+			s = expr + ".Format(time.RFC3339)"
+		}
+		return sink(s) + "\n"
+	}
+
+	b := t.Underlying().(*types.Basic)
+	if b.Info()&types.IsBoolean != 0 && f.opts.Contains("int") {
+		return fmt.Sprintf("if %s {\n%s\n} else {\n%s\n}\n", expr, sink(`"1"`), sink(`"0"`))
+	}
+
+	var s string
+	switch {
+	case hasFormatMethod(t) || b.Info()&types.IsComplex != 0:
+		g.imports["fmt"] = true
+		s = "fmt.Sprint(" + expr + ")"
+	case b.Info()&types.IsString != 0:
+		s = convert(expr, t, types.String)
+	case b.Info()&types.IsBoolean != 0:
+		g.imports["strconv"] = true
+		s = "strconv.FormatBool(" + convert(expr, t, types.Bool) + ")"
+	case b.Info()&types.IsFloat != 0:
+		bits := "64"
+		if b.Kind() == types.Float32 {
+			bits = "32"
+		}
+		g.imports["strconv"] = true
+		s = "strconv.FormatFloat(" + convert(expr, t, types.Float64) + ", 'g', -1, " + bits + ")"
+	case b.Info()&types.IsUnsigned != 0:
+		g.imports["strconv"] = true
+		s = "strconv.FormatUint(" + convert(expr, t, types.Uint64) + ", 10)"
+	default:
+		g.imports["strconv"] = true
+		s = "strconv.FormatInt(" + convert(expr, t, types.Int64) + ", 10)"
+	}
+	return sink(s) + "\n"
+}
+
+// isEncoder reports whether t implements query.Encoder, once the methods
+// being generated are declared.
+func (g *generator) isEncoder(t types.Type) bool {
+	if n, ok := deref(t).(*types.Named); ok && g.generating[n] {
+		return true
+	}
+	return types.Implements(t, g.encoder)
+}
+
+// isGenerated reports whether t has, or is being given, an EncodeValues method
+// generated by querygen.
+func (g *generator) isGenerated(t types.Type) bool {
+	if n, ok := deref(t).(*types.Named); ok && g.generating[n] {
+		return true
+	}
+	return types.NewMethodSet(t).Lookup(nil, "QuerygenEncoder") != nil
+}
+
+// isScalar reports whether values of type t are encoded as a single string
+// that scalarCode can produce.
+func (g *generator) isScalar(t types.Type) bool {
+	if isTime(t) {
+		return true
+	}
+	b, ok := t.Underlying().(*types.Basic)
+	return ok && b.Info()&(types.IsBoolean|types.IsNumeric|types.IsString) != 0
+}
+
+// format returns the formatted source of the generated file.
+func (g *generator) format(header string) ([]byte, error) {
+	var std, other []string
+	for path := range g.imports {
+		if strings.Contains(strings.SplitN(path, "/", 2)[0], ".") {
+			other = append(other, path)
+		} else {
+			std = append(std, path)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(other)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by %q; DO NOT EDIT.\n\n", header)
+	fmt.Fprintf(&b, "package %s\n\nimport (\n", g.pkg.Name())
+	for _, path := range std {
+		fmt.Fprintf(&b, "%q\n", path)
+	}
+	if len(std) > 0 && len(other) > 0 {
+		b.WriteString("\n")
+	}
+	for _, path := range other {
+		fmt.Fprintf(&b, "%q\n", path)
+	}
+	b.WriteString(")\n\n")
+	b.Write(g.buf.Bytes())
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %v", err)
+	}
+	return src, nil
+}
+
+// nonEmpty returns the condition under which the value of type t reached by
+// expr is not empty, as defined for the "omitempty" option.  The condition is
+// "" if the value is never empty, and nonEmpty reports false if the condition
+// cannot be expressed directly.
+func (g *generator) nonEmpty(expr string, t types.Type) (string, bool) {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsBoolean != 0:
+			return expr, true
+		case u.Info()&types.IsString != 0:
+			return expr + ` != ""`, true
+		case u.Info()&types.IsNumeric != 0:
+			return expr + " != 0", true
+		}
+		return "", false
+	case *types.Array, *types.Slice, *types.Map:
+		return "len(" + expr + ") != 0", true
+	case *types.Pointer, *types.Interface:
+		return expr + " != nil", true
+	case *types.Struct:
+		if sel := types.NewMethodSet(t).Lookup(nil, "IsZero"); sel != nil {
+			sig := sel.Type().(*types.Signature)
+			if sig.Params().Len() == 0 && sig.Results().Len() == 1 &&
+				types.Identical(sig.Results().At(0).Type(), types.Typ[types.Bool]) {
+				return "!" + expr + ".IsZero()", true
+			}
+		}
+		if g.isEncoder(t) && !g.isGenerated(t) {
+			return "", true
+		}
+		// other structs, including those given generated EncodeValues
+		// methods, are empty if all of their fields are, which is left to
+		// query.EncodeField
+		return "", false
+	}
+	return "", false
+}
+
+// hasFormatMethod reports whether fmt.Sprint formats values of type t using
+// one of their methods.
+func hasFormatMethod(t types.Type) bool {
+	ms := types.NewMethodSet(t)
+	for _, name := range []string{"Format", "Error", "String"} {
+		if ms.Lookup(nil, name) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// convert returns expr converted from type t to the basic type kind, if they
+// differ.
+func convert(expr string, t types.Type, kind types.BasicKind) string {
+	if types.Identical(t, types.Typ[kind]) {
+		return expr
+	}
+	return types.Typ[kind].Name() + "(" + expr + ")"
+}
+
+// isTime reports whether t is time.Time.
+func isTime(t types.Type) bool {
+	n, ok := t.(*types.Named)
+	return ok && n.Obj().Pkg() != nil && n.Obj().Pkg().Path() == "time" && n.Obj().Name() == "Time"
+}
+
+// isSequence reports whether t is a slice or array type.
+func isSequence(t types.Type) bool {
+	switch t.Underlying().(type) {
+	case *types.Slice, *types.Array:
+		return true
+	}
+	return false
+}
+
+// elem returns the element type of the slice or array type t.
+func elem(t types.Type) types.Type {
+	switch u := t.Underlying().(type) {
+	case *types.Slice:
+		return u.Elem()
+	case *types.Array:
+		return u.Elem()
+	}
+	return nil
+}
+
+// quote returns s as a Go string literal, using a raw string if possible.
+func quote(s string) string {
+	if strings.Contains(s, "`") || !strconv.CanBackquote(s) {
+		return strconv.Quote(s)
+	}
+	return "`" + s + "`"
+}
+
+// contains reports whether list contains s.
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// Test that the generated code in the example package is up to date.  The
+// generated tests in that package check that the code is correct.
+func TestGenerate_Example(t *testing.T) {
+	dir := filepath.Join("internal", "example")
+	files := []string{"options_query.go", "options_query_test.go"}
+	g, err := newGenerator(dir, files)
+	if err != nil {
+		t.Fatalf("newGenerator returned error: %v", err)
+	}
+
+	header := "querygen -type=Options,User,Address -test"
+	types := []string{"Options", "User", "Address"}
+	src, err := g.generate(header, types)
+	if err != nil {
+		t.Fatalf("generate returned error: %v", err)
+	}
+	testSrc, err := g.generateTest(header, types)
+	if err != nil {
+		t.Fatalf("generateTest returned error: %v", err)
+	}
+
+	for i, got := range [][]byte{src, testSrc} {
+		want, err := ioutil.ReadFile(filepath.Join(dir, files[i]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(string(want), string(got)); diff != "" {
+			t.Errorf("%s is out of date, run go generate:\n%s", files[i], diff)
+		}
+	}
+}
+
+func TestGenerate_Errors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "querygen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := `package p
+
+import "net/url"
+
+type NotStruct int
+
+type Custom struct{ A string }
+
+func (Custom) EncodeValues(key string, v *url.Values) error { return nil }
+
+type Promoted struct{ Custom }
+
+type Inner struct{ A string }
+
+type Outer struct{ *Inner }
+`
+	if err := ioutil.WriteFile(filepath.Join(dir, "p.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	g, err := newGenerator(dir, nil)
+	if err != nil {
+		t.Fatalf("newGenerator returned error: %v", err)
+	}
+
+	tests := []struct {
+		types []string
+		want  string
+	}{
+		{[]string{"Missing"}, "not found"},
+		{[]string{"NotStruct"}, "not a struct type"},
+		{[]string{"Custom"}, "already has an EncodeValues method"},
+		{[]string{"Promoted"}, "promoted from an embedded field"},
+		{[]string{"Outer", "Inner"}, "promoted from embedded type Inner"},
+	}
+
+	for _, tt := range tests {
+		_, err := g.generate("querygen", tt.types)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("generate(%q) returned error %v, want %q", tt.types, err, tt.want)
+		}
+	}
+
+	if _, err := g.generate("querygen", []string{"Inner", "Outer"}); err == nil {
+		t.Errorf("generate did not return an error")
+	}
+	if _, err := g.generate("querygen", []string{"Outer"}); err != nil {
+		t.Errorf("generate returned error: %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package example declares types covering the field types and options
+// supported by querygen.  Its generated tests check that the generated code
+// encodes them exactly as query.Values does.
+package example
+
+import (
+	"strings"
+	"time"
+)
+
+//go:generate go run github.com/google/go-querystring/cmd/querygen -type=Options,User,Address -test
+
+type Options struct {
+	Query    string  `url:"q"`
+	ShowAll  bool    `url:"all"`
+	Page     int     `url:"page,omitempty"`
+	Ratio    float64 `url:"ratio"`
+	Small    float32
+	Flag     bool   `url:"flag,int"`
+	State    State  `url:"state,omitempty"`
+	Kind     Kind   `url:"kind"`
+	Limit    *int   `url:"limit"`
+	Offset   *uint8 `url:"offset,omitempty"`
+	Num      complex64
+	Since    time.Time         `url:"since,unix"`
+	Until    *time.Time        `url:"until,omitempty"`
+	Day      time.Time         `layout:"2006-01-02"`
+	Modified time.Time         `url:"modified,httpdate"`
+	Created  time.Time         `url:"created,unixmilli,omitempty"`
+	Tags     []string          `url:"tag,brackets"`
+	IDs      []int             `url:"ids,comma"`
+	Bits     []bool            `url:"bits,int" del:"!"`
+	Steps    [2]uint8          `url:"step,numbered"`
+	Colors   []Kind            `url:"color,pipeDelimited"`
+	States   []State           `url:"states,form,noexplode"`
+	Times    []time.Time       `url:"times,unixnano"`
+	Labels   []string          `url:"label,comma,omitemptyelem"`
+	Levels   [3]bool           `url:"level,numbered,int,omitemptyelem"`
+	Aliases  []Kind            `url:"alias,omitemptyelem"`
+	Scopes   []string          `url:"scope,brackets,keepempty"`
+	Grants   []int             `url:"grant,comma,keepempty"`
+	Refs     []string          `url:"ref,indexed"`
+	Ranks    [2]int            `url:"rank,indexed=1,omitemptyelem"`
+	Paths    []string          `url:"path,pipe,escape"`
+	Codes    []Kind            `url:"code,del=::,escape,omitemptyelem"`
+	Notes    []string          `url:"note,comma,escape=percent"`
+	Quoted   []string          `url:"quoted,semicolon,escape=quote"`
+	Owner    User              `url:"owner"`
+	Author   *User             `url:"author,omitempty"`
+	Shipping Address           `url:"shipping,form,noexplode"`
+	Filter   map[string]string `url:"filter,deepObject"`
+	Extra    interface{}       `url:"extra,omitempty"`
+	Hidden   string            `url:"-"`
+	Token    string            `header:"X-Token"`
+	ID       string            `path:"id"`
+	Region   string            `url:"region" path:"-"`
+	Paging
+	*Meta
+	Sort   *Sort `url:",inline"`
+	Window Range `url:",prefix=window_,suffix=_at"`
+	Tree   Node  `url:",prefix=tree_"`
+}
+
+// Sort is inlined in Options.
+type Sort struct {
+	Order string   `url:"order,omitempty"`
+	By    []string `url:"by,comma"`
+}
+
+// Range is flattened in Options with a prefix and suffix.
+type Range struct {
+	Start time.Time `url:"start,unix,omitempty"`
+	End   time.Time `url:"end,unix,omitempty"`
+}
+
+// Node is recursive, so its Child field is encoded as a nested struct rather
+// than flattened.
+type Node struct {
+	Name  string `url:"name"`
+	Child *Node  `url:",prefix=child_"`
+}
+
+// State is a string with a String method.
+type State string
+
+func (s State) String() string {
+	return strings.ToUpper(string(s))
+}
+
+// Kind is a string without methods.
+type Kind string
+
+// Paging is embedded in Options.  Its Page field is hidden by Options.Page.
+type Paging struct {
+	Page    int `url:"page"`
+	PerPage int `url:"per_page,omitempty"`
+}
+
+// Meta is embedded in Options by pointer.
+type Meta struct {
+	Source string `url:"source"`
+	Count  int
+}
+
+type User struct {
+	Name    string  `url:"name"`
+	Address Address `url:"addr"`
+	Point   Point   `url:"point,form,noexplode"`
+	Home    Address `url:"home,omitempty"`
+}
+
+type Address struct {
+	Street string `url:"street"`
+	City   string `url:"city,omitempty"`
+}
+
+// Point is not generated, so is encoded using reflection.
+type Point struct {
+	X, Y int
+}
@@ -0,0 +1,315 @@
+// Code generated by "querygen -type=Options,User,Address -test"; DO NOT EDIT.
+
+package example
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-querystring/query"
+)
+
+// EncodeValues implements query.Encoder, encoding the fields of x as
+// query.Values does.
+func (x Options) EncodeValues(scope string, v *url.Values) error {
+	prefix, suffix := "", ""
+	if scope != "" {
+		prefix, suffix = scope+"[", "]"
+	}
+
+	v.Add(prefix+"q"+suffix, x.Query)
+	v.Add(prefix+"all"+suffix, strconv.FormatBool(x.ShowAll))
+	if x.Page != 0 {
+		v.Add(prefix+"page"+suffix, strconv.FormatInt(int64(x.Page), 10))
+	}
+	v.Add(prefix+"ratio"+suffix, strconv.FormatFloat(x.Ratio, 'g', -1, 64))
+	v.Add(prefix+"Small"+suffix, strconv.FormatFloat(float64(x.Small), 'g', -1, 32))
+	if x.Flag {
+		v.Add(prefix+"flag"+suffix, "1")
+	} else {
+		v.Add(prefix+"flag"+suffix, "0")
+	}
+	if x.State != "" {
+		v.Add(prefix+"state"+suffix, fmt.Sprint(x.State))
+	}
+	v.Add(prefix+"kind"+suffix, string(x.Kind))
+	if x.Limit != nil {
+		v.Add(prefix+"limit"+suffix, strconv.FormatInt(int64(*x.Limit), 10))
+	} else {
+		v.Add(prefix+"limit"+suffix, "")
+	}
+	if x.Offset != nil {
+		v.Add(prefix+"offset"+suffix, strconv.FormatUint(uint64(*x.Offset), 10))
+	}
+	v.Add(prefix+"Num"+suffix, fmt.Sprint(x.Num))
+	v.Add(prefix+"since"+suffix, strconv.FormatInt(x.Since.Unix(), 10))
+	if x.Until != nil {
+		v.Add(prefix+"until"+suffix, (*x.Until).Format(time.RFC3339))
+	}
+	v.Add(prefix+"Day"+suffix, x.Day.Format("2006-01-02"))
+	v.Add(prefix+"modified"+suffix, x.Modified.UTC().Format(http.TimeFormat))
+	if !x.Created.IsZero() {
+		v.Add(prefix+"created"+suffix, strconv.FormatInt(x.Created.UnixNano()/1e6, 10))
+	}
+	for _, e := range x.Tags {
+		v.Add(prefix+"tag"+suffix+"[]", e)
+	}
+	if len(x.IDs) > 0 {
+		var b strings.Builder
+		for i, e := range x.IDs {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			b.WriteString(strconv.FormatInt(int64(e), 10))
+		}
+		v.Add(prefix+"ids"+suffix, b.String())
+	}
+	if len(x.Bits) > 0 {
+		var b strings.Builder
+		for i, e := range x.Bits {
+			if i > 0 {
+				b.WriteString("!")
+			}
+			if e {
+				b.WriteString("1")
+			} else {
+				b.WriteString("0")
+			}
+		}
+		v.Add(prefix+"bits"+suffix, b.String())
+	}
+	for i, e := range x.Steps {
+		v.Add(prefix+"step"+suffix+strconv.Itoa(i), strconv.FormatUint(uint64(e), 10))
+	}
+	if len(x.Colors) > 0 {
+		var b strings.Builder
+		for i, e := range x.Colors {
+			if i > 0 {
+				b.WriteString("|")
+			}
+			b.WriteString(string(e))
+		}
+		v.Add(prefix+"color"+suffix, b.String())
+	}
+	if len(x.States) > 0 {
+		var b strings.Builder
+		for i, e := range x.States {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			b.WriteString(fmt.Sprint(e))
+		}
+		v.Add(prefix+"states"+suffix, b.String())
+	}
+	for _, e := range x.Times {
+		v.Add(prefix+"times"+suffix, strconv.FormatInt(e.UnixNano(), 10))
+	}
+	if len(x.Labels) > 0 {
+		var b strings.Builder
+		n := 0
+		for _, e := range x.Labels {
+			if e != "" {
+				if n > 0 {
+					b.WriteString(",")
+				}
+				n++
+				b.WriteString(e)
+			}
+		}
+		if n > 0 {
+			v.Add(prefix+"label"+suffix, b.String())
+		}
+	}
+	if len(x.Levels) > 0 {
+		n := 0
+		for _, e := range x.Levels {
+			if e {
+				if e {
+					v.Add(prefix+"level"+suffix+strconv.Itoa(n), "1")
+				} else {
+					v.Add(prefix+"level"+suffix+strconv.Itoa(n), "0")
+				}
+				n++
+			}
+		}
+	}
+	for _, e := range x.Aliases {
+		if e != "" {
+			v.Add(prefix+"alias"+suffix, string(e))
+		}
+	}
+	for _, e := range x.Scopes {
+		v.Add(prefix+"scope"+suffix+"[]", e)
+	}
+	if x.Scopes != nil && len(x.Scopes) == 0 {
+		v.Add(prefix+"scope"+suffix+"[]", "")
+	}
+	if len(x.Grants) > 0 {
+		var b strings.Builder
+		for i, e := range x.Grants {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			b.WriteString(strconv.FormatInt(int64(e), 10))
+		}
+		v.Add(prefix+"grant"+suffix, b.String())
+	}
+	if x.Grants != nil && len(x.Grants) == 0 {
+		v.Add(prefix+"grant"+suffix, "")
+	}
+	for i, e := range x.Refs {
+		v.Add(prefix+"ref"+suffix+"["+strconv.Itoa(i)+"]", e)
+	}
+	if len(x.Ranks) > 0 {
+		n := 0
+		for _, e := range x.Ranks {
+			if e != 0 {
+				v.Add(prefix+"rank"+suffix+"["+strconv.Itoa(n+1)+"]", strconv.FormatInt(int64(e), 10))
+				n++
+			}
+		}
+	}
+	if len(x.Paths) > 0 {
+		var b strings.Builder
+		r := strings.NewReplacer("\\", "\\\\", "|", "\\|")
+		for i, e := range x.Paths {
+			if i > 0 {
+				b.WriteString("|")
+			}
+			r.WriteString(&b, e)
+		}
+		v.Add(prefix+"path"+suffix, b.String())
+	}
+	if len(x.Codes) > 0 {
+		var b strings.Builder
+		r := strings.NewReplacer("\\", "\\\\", "::", "\\::")
+		n := 0
+		for _, e := range x.Codes {
+			if e != "" {
+				if n > 0 {
+					b.WriteString("::")
+				}
+				n++
+				r.WriteString(&b, string(e))
+			}
+		}
+		if n > 0 {
+			v.Add(prefix+"code"+suffix, b.String())
+		}
+	}
+	if len(x.Notes) > 0 {
+		var b strings.Builder
+		r := strings.NewReplacer("%", "%25", ",", "%2C")
+		for i, e := range x.Notes {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			r.WriteString(&b, e)
+		}
+		v.Add(prefix+"note"+suffix, b.String())
+	}
+	if err := query.EncodeField(v, scope, "quoted", &x.Quoted, `url:"quoted,semicolon,escape=quote"`); err != nil {
+		return err
+	}
+	if err := x.Owner.EncodeValues(prefix+"owner"+suffix, v); err != nil {
+		return err
+	}
+	if err := query.EncodeField(v, scope, "author", &x.Author, `url:"author,omitempty"`); err != nil {
+		return err
+	}
+	if err := query.EncodeField(v, scope, "shipping", &x.Shipping, `url:"shipping,form,noexplode"`); err != nil {
+		return err
+	}
+	if err := query.EncodeField(v, scope, "filter", &x.Filter, `url:"filter,deepObject"`); err != nil {
+		return err
+	}
+	if err := query.EncodeField(v, scope, "extra", &x.Extra, `url:"extra,omitempty"`); err != nil {
+		return err
+	}
+	v.Add(prefix+"region"+suffix, x.Region)
+	if x.Paging.PerPage != 0 {
+		v.Add(prefix+"per_page"+suffix, strconv.FormatInt(int64(x.Paging.PerPage), 10))
+	}
+	if x.Meta != nil {
+		v.Add(prefix+"source"+suffix, x.Meta.Source)
+		v.Add(prefix+"Count"+suffix, strconv.FormatInt(int64(x.Meta.Count), 10))
+	}
+	if x.Sort != nil {
+		if x.Sort.Order != "" {
+			v.Add(prefix+"order"+suffix, x.Sort.Order)
+		}
+		if len(x.Sort.By) > 0 {
+			var b strings.Builder
+			for i, e := range x.Sort.By {
+				if i > 0 {
+					b.WriteString(",")
+				}
+				b.WriteString(e)
+			}
+			v.Add(prefix+"by"+suffix, b.String())
+		}
+	}
+	if !x.Window.Start.IsZero() {
+		v.Add(prefix+"window_start_at"+suffix, strconv.FormatInt(x.Window.Start.Unix(), 10))
+	}
+	if !x.Window.End.IsZero() {
+		v.Add(prefix+"window_end_at"+suffix, strconv.FormatInt(x.Window.End.Unix(), 10))
+	}
+	v.Add(prefix+"tree_name"+suffix, x.Tree.Name)
+	if err := query.EncodeField(v, scope, "tree_Child", &x.Tree.Child, `url:",prefix=child_"`); err != nil {
+		return err
+	}
+	return nil
+}
+
+// QuerygenEncoder marks EncodeValues as generated, so that query.Values
+// calls it to encode x.
+func (Options) QuerygenEncoder() {}
+
+// EncodeValues implements query.Encoder, encoding the fields of x as
+// query.Values does.
+func (x User) EncodeValues(scope string, v *url.Values) error {
+	prefix, suffix := "", ""
+	if scope != "" {
+		prefix, suffix = scope+"[", "]"
+	}
+
+	v.Add(prefix+"name"+suffix, x.Name)
+	if err := x.Address.EncodeValues(prefix+"addr"+suffix, v); err != nil {
+		return err
+	}
+	if err := query.EncodeField(v, scope, "point", &x.Point, `url:"point,form,noexplode"`); err != nil {
+		return err
+	}
+	if err := query.EncodeField(v, scope, "home", &x.Home, `url:"home,omitempty"`); err != nil {
+		return err
+	}
+	return nil
+}
+
+// QuerygenEncoder marks EncodeValues as generated, so that query.Values
+// calls it to encode x.
+func (User) QuerygenEncoder() {}
+
+// EncodeValues implements query.Encoder, encoding the fields of x as
+// query.Values does.
+func (x Address) EncodeValues(scope string, v *url.Values) error {
+	prefix, suffix := "", ""
+	if scope != "" {
+		prefix, suffix = scope+"[", "]"
+	}
+
+	v.Add(prefix+"street"+suffix, x.Street)
+	if x.City != "" {
+		v.Add(prefix+"city"+suffix, x.City)
+	}
+	return nil
+}
+
+// QuerygenEncoder marks EncodeValues as generated, so that query.Values
+// calls it to encode x.
+func (Address) QuerygenEncoder() {}
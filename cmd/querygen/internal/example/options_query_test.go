@@ -0,0 +1,192 @@
+// Code generated by "querygen -type=Options,User,Address -test"; DO NOT EDIT.
+
+package example
+
+import (
+	"math/rand"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-querystring/query"
+)
+
+func TestEncodeValues_Options(t *testing.T) {
+	// reflected has the fields of Options but not its methods, so query.Values
+	// encodes it using reflection.
+	type reflected Options
+	type scoped struct {
+		V reflected `url:"scope"`
+	}
+
+	// styled checks that fields of type Options with OpenAPI styles, which
+	// are encoded by reflection, are encoded as those of type reflected.
+	type styled struct {
+		F Options `url:"f,form"`
+		N Options `url:"n,form,noexplode"`
+	}
+	type styledReflected struct {
+		F reflected `url:"f,form"`
+		N reflected `url:"n,form,noexplode"`
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		var x Options
+		querygenFillOptions(reflect.ValueOf(&x).Elem(), r, 0)
+		querygenCheckOptions(t, x, "", reflected(x))
+		querygenCheckOptions(t, x, "scope", scoped{reflected(x)})
+		querygenCompareOptions(t, styled{x, x}, styledReflected{reflected(x), reflected(x)})
+	}
+}
+
+func TestEncodeValues_User(t *testing.T) {
+	// reflected has the fields of User but not its methods, so query.Values
+	// encodes it using reflection.
+	type reflected User
+	type scoped struct {
+		V reflected `url:"scope"`
+	}
+
+	// styled checks that fields of type User with OpenAPI styles, which
+	// are encoded by reflection, are encoded as those of type reflected.
+	type styled struct {
+		F User `url:"f,form"`
+		N User `url:"n,form,noexplode"`
+	}
+	type styledReflected struct {
+		F reflected `url:"f,form"`
+		N reflected `url:"n,form,noexplode"`
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		var x User
+		querygenFillOptions(reflect.ValueOf(&x).Elem(), r, 0)
+		querygenCheckOptions(t, x, "", reflected(x))
+		querygenCheckOptions(t, x, "scope", scoped{reflected(x)})
+		querygenCompareOptions(t, styled{x, x}, styledReflected{reflected(x), reflected(x)})
+	}
+}
+
+func TestEncodeValues_Address(t *testing.T) {
+	// reflected has the fields of Address but not its methods, so query.Values
+	// encodes it using reflection.
+	type reflected Address
+	type scoped struct {
+		V reflected `url:"scope"`
+	}
+
+	// styled checks that fields of type Address with OpenAPI styles, which
+	// are encoded by reflection, are encoded as those of type reflected.
+	type styled struct {
+		F Address `url:"f,form"`
+		N Address `url:"n,form,noexplode"`
+	}
+	type styledReflected struct {
+		F reflected `url:"f,form"`
+		N reflected `url:"n,form,noexplode"`
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		var x Address
+		querygenFillOptions(reflect.ValueOf(&x).Elem(), r, 0)
+		querygenCheckOptions(t, x, "", reflected(x))
+		querygenCheckOptions(t, x, "scope", scoped{reflected(x)})
+		querygenCompareOptions(t, styled{x, x}, styledReflected{reflected(x), reflected(x)})
+	}
+}
+
+// querygenCheckOptions checks that x encodes within scope exactly as
+// query.Values encodes reflected.
+func querygenCheckOptions(t *testing.T, x query.Encoder, scope string, reflected interface{}) {
+	t.Helper()
+	want, wantErr := query.Values(reflected)
+	got := make(url.Values)
+	err := x.EncodeValues(scope, &got)
+	if (err != nil) != (wantErr != nil) {
+		t.Fatalf("EncodeValues(%q) of %#v returned error %v, query.Values returned %v", scope, x, err, wantErr)
+	}
+	if err == nil && !reflect.DeepEqual(got, want) {
+		t.Fatalf("EncodeValues(%q) of %#v = %v, query.Values = %v", scope, x, got, want)
+	}
+}
+
+// querygenCompareOptions checks that query.Values encodes x exactly as
+// it encodes reflected.
+func querygenCompareOptions(t *testing.T, x, reflected interface{}) {
+	t.Helper()
+	want, wantErr := query.Values(reflected)
+	got, err := query.Values(x)
+	if (err != nil) != (wantErr != nil) {
+		t.Fatalf("query.Values of %#v returned error %v, want %v", x, err, wantErr)
+	}
+	if err == nil && !reflect.DeepEqual(got, want) {
+		t.Fatalf("query.Values of %#v = %v, want %v", x, got, want)
+	}
+}
+
+// querygenFillOptions sets v and its exported fields to random values.
+func querygenFillOptions(v reflect.Value, r *rand.Rand, depth int) {
+	if !v.CanSet() || depth > 4 {
+		return
+	}
+	if v.Type() == reflect.TypeOf(time.Time{}) {
+		v.Set(reflect.ValueOf(time.Unix(r.Int63n(1e10), r.Int63n(1e9)).UTC()))
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(r.Intn(2) == 1)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(r.Int63n(256) - 128)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		v.SetUint(uint64(r.Intn(256)))
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(r.NormFloat64() * 1000)
+	case reflect.String:
+		chars := []rune("ab Z,;|&=[]%é")
+		s := make([]rune, r.Intn(4))
+		for i := range s {
+			s[i] = chars[r.Intn(len(chars))]
+		}
+		v.SetString(string(s))
+	case reflect.Ptr:
+		if r.Intn(4) > 0 {
+			p := reflect.New(v.Type().Elem())
+			querygenFillOptions(p.Elem(), r, depth+1)
+			v.Set(p)
+		}
+	case reflect.Slice:
+		n := r.Intn(4)
+		s := reflect.MakeSlice(v.Type(), n, n)
+		for i := 0; i < n; i++ {
+			querygenFillOptions(s.Index(i), r, depth+1)
+		}
+		v.Set(s)
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			querygenFillOptions(v.Index(i), r, depth+1)
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return
+		}
+		m := reflect.MakeMap(v.Type())
+		for n := r.Intn(4); n > 0; n-- {
+			k := reflect.New(v.Type().Key()).Elem()
+			e := reflect.New(v.Type().Elem()).Elem()
+			querygenFillOptions(k, r, depth+1)
+			querygenFillOptions(e, r, depth+1)
+			m.SetMapIndex(k, e)
+		}
+		v.Set(m)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			querygenFillOptions(v.Field(i), r, depth+1)
+		}
+	}
+}
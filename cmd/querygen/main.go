@@ -0,0 +1,111 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Querygen generates EncodeValues methods for struct types, so that they can
+// be encoded by the query package without reflection.
+//
+// Given the name of one or more struct types in a package, querygen writes a
+// new Go source file containing an EncodeValues method for each type that
+// produces the same url.Values as query.Values would, reading the same "url",
+// "del" and "layout" struct tags.  Because these methods implement
+// query.Encoder, query.Values calls them in place of its reflection based
+// encoding, both for the types themselves and for struct fields of the types.
+//
+// Usage:
+//
+//	querygen -type T[,T...] [-output file] [-test] [directory]
+//
+// It is typically invoked by a go:generate comment in the package containing
+// the types:
+//
+//	//go:generate querygen -type=Options
+//
+// The file is written to the package directory, named after the first type
+// by default: Options produces options_query.go.  With the -test flag,
+// querygen also writes options_query_test.go, which fills values of each
+// type with random data and checks that the generated methods encode them
+// exactly as reflection does.  Tests should be regenerated along with the
+// methods whenever the types change.
+//
+// Fields are encoded directly when they are strings, booleans or numbers,
+// time.Time values, pointers to or slices and arrays of those, or struct
+// values whose type implements query.Encoder, such as other generated types.
+// Other fields, such as maps, interfaces and structs with OpenAPI style
+// options, are encoded by query.EncodeField, which uses reflection.
+//
+// Config options such as DisallowConflicts do not apply to generated methods,
+// and file fields are not detected by query.WriteMultipart.  Querygen refuses
+// types that already have an EncodeValues method, including one promoted
+// from an embedded field, since query.Values would use that method instead.
+// Decoding is not supported, as the query package only encodes values.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	typeNames = flag.String("type", "", "comma-separated list of type names; must be set")
+	output    = flag.String("output", "", "output file name; default <type>_query.go")
+	withTests = flag.Bool("test", false, "also write a test checking the generated methods against query.Values")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage of querygen:\n")
+	fmt.Fprintf(os.Stderr, "\tquerygen -type T[,T...] [-output file] [-test] [directory]\n")
+	fmt.Fprintf(os.Stderr, "Flags:\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("querygen: ")
+	flag.Usage = usage
+	flag.Parse()
+	if *typeNames == "" || flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dir := "."
+	if flag.NArg() == 1 {
+		dir = flag.Arg(0)
+	}
+	types := strings.Split(*typeNames, ",")
+	name := *output
+	if name == "" {
+		name = strings.ToLower(types[0]) + "_query.go"
+	}
+	testName := strings.TrimSuffix(name, ".go") + "_test.go"
+
+	g, err := newGenerator(dir, []string{name, testName})
+	if err != nil {
+		log.Fatal(err)
+	}
+	header := "querygen " + strings.Join(os.Args[1:], " ")
+
+	src, err := g.generate(header, types)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, name), src, 0644); err != nil {
+		log.Fatal(err)
+	}
+
+	if *withTests {
+		src, err := g.generateTest(header, types)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, testName), src, 0644); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"text/template"
+)
+
+// generateTest returns the source of a test file checking that the generated
+// EncodeValues methods of the named types agree with query.Values.
+func (g *generator) generateTest(header string, names []string) ([]byte, error) {
+	for _, name := range names {
+		if _, err := g.lookup(name); err != nil {
+			return nil, err
+		}
+	}
+
+	var b bytes.Buffer
+	err := testTemplate.Execute(&b, struct {
+		Header  string
+		Package string
+		Types   []string
+	}{header, g.pkg.Name(), names})
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated test: %v", err)
+	}
+	return src, nil
+}
+
+// testTemplate is the template for generated tests.  The helper functions
+// are named after the first type, so that tests generated for several sets
+// of types may live in the same package.
+var testTemplate = template.Must(template.New("test").Parse(`// Code generated by {{printf "%q" .Header}}; DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"math/rand"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-querystring/query"
+)
+{{$helper := index .Types 0}}
+{{range .Types}}
+func TestEncodeValues_{{.}}(t *testing.T) {
+	// reflected has the fields of {{.}} but not its methods, so query.Values
+	// encodes it using reflection.
+	type reflected {{.}}
+	type scoped struct {
+		V reflected ` + "`" + `url:"scope"` + "`" + `
+	}
+
+	// styled checks that fields of type {{.}} with OpenAPI styles, which
+	// are encoded by reflection, are encoded as those of type reflected.
+	type styled struct {
+		F {{.}} ` + "`" + `url:"f,form"` + "`" + `
+		N {{.}} ` + "`" + `url:"n,form,noexplode"` + "`" + `
+	}
+	type styledReflected struct {
+		F reflected ` + "`" + `url:"f,form"` + "`" + `
+		N reflected ` + "`" + `url:"n,form,noexplode"` + "`" + `
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		var x {{.}}
+		querygenFill{{$helper}}(reflect.ValueOf(&x).Elem(), r, 0)
+		querygenCheck{{$helper}}(t, x, "", reflected(x))
+		querygenCheck{{$helper}}(t, x, "scope", scoped{reflected(x)})
+		querygenCompare{{$helper}}(t, styled{x, x}, styledReflected{reflected(x), reflected(x)})
+	}
+}
+{{end}}
+// querygenCheck{{$helper}} checks that x encodes within scope exactly as
+// query.Values encodes reflected.
+func querygenCheck{{$helper}}(t *testing.T, x query.Encoder, scope string, reflected interface{}) {
+	t.Helper()
+	want, wantErr := query.Values(reflected)
+	got := make(url.Values)
+	err := x.EncodeValues(scope, &got)
+	if (err != nil) != (wantErr != nil) {
+		t.Fatalf("EncodeValues(%q) of %#v returned error %v, query.Values returned %v", scope, x, err, wantErr)
+	}
+	if err == nil && !reflect.DeepEqual(got, want) {
+		t.Fatalf("EncodeValues(%q) of %#v = %v, query.Values = %v", scope, x, got, want)
+	}
+}
+
+// querygenCompare{{$helper}} checks that query.Values encodes x exactly as
+// it encodes reflected.
+func querygenCompare{{$helper}}(t *testing.T, x, reflected interface{}) {
+	t.Helper()
+	want, wantErr := query.Values(reflected)
+	got, err := query.Values(x)
+	if (err != nil) != (wantErr != nil) {
+		t.Fatalf("query.Values of %#v returned error %v, want %v", x, err, wantErr)
+	}
+	if err == nil && !reflect.DeepEqual(got, want) {
+		t.Fatalf("query.Values of %#v = %v, want %v", x, got, want)
+	}
+}
+
+// querygenFill{{$helper}} sets v and its exported fields to random values.
+func querygenFill{{$helper}}(v reflect.Value, r *rand.Rand, depth int) {
+	if !v.CanSet() || depth > 4 {
+		return
+	}
+	if v.Type() == reflect.TypeOf(time.Time{}) {
+		v.Set(reflect.ValueOf(time.Unix(r.Int63n(1e10), r.Int63n(1e9)).UTC()))
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(r.Intn(2) == 1)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(r.Int63n(256) - 128)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		v.SetUint(uint64(r.Intn(256)))
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(r.NormFloat64() * 1000)
+	case reflect.String:
+		chars := []rune("ab Z,;|&=[]%é")
+		s := make([]rune, r.Intn(4))
+		for i := range s {
+			s[i] = chars[r.Intn(len(chars))]
+		}
+		v.SetString(string(s))
+	case reflect.Ptr:
+		if r.Intn(4) > 0 {
+			p := reflect.New(v.Type().Elem())
+			querygenFill{{$helper}}(p.Elem(), r, depth+1)
+			v.Set(p)
+		}
+	case reflect.Slice:
+		n := r.Intn(4)
+		s := reflect.MakeSlice(v.Type(), n, n)
+		for i := 0; i < n; i++ {
+			querygenFill{{$helper}}(s.Index(i), r, depth+1)
+		}
+		v.Set(s)
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			querygenFill{{$helper}}(v.Index(i), r, depth+1)
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return
+		}
+		m := reflect.MakeMap(v.Type())
+		for n := r.Intn(4); n > 0; n-- {
+			k := reflect.New(v.Type().Key()).Elem()
+			e := reflect.New(v.Type().Elem()).Elem()
+			querygenFill{{$helper}}(k, r, depth+1)
+			querygenFill{{$helper}}(e, r, depth+1)
+			m.SetMapIndex(k, e)
+		}
+		v.Set(m)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			querygenFill{{$helper}}(v.Field(i), r, depth+1)
+		}
+	}
+}
+`))
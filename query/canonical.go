@@ -0,0 +1,110 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package query
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// A CanonicalMode selects the rules used by Canonical to encode a query
+// string for signing.
+type CanonicalMode int
+
+const (
+	// CanonicalRFC3986 percent-encodes keys and values as described by
+	// RFC 3986, leaving only the unreserved characters A-Z, a-z, 0-9, "-",
+	// ".", "_" and "~" unencoded, so that a space is encoded as "%20" rather
+	// than "+".  Parameters are sorted by encoded key and then by encoded
+	// value, in byte order, and every parameter includes an "=", even if its
+	// value is empty.
+	CanonicalRFC3986 CanonicalMode = iota
+
+	// CanonicalSigV4 produces the canonical query string of AWS Signature
+	// Version 4.  It follows the rules of CanonicalRFC3986, and omits the
+	// X-Amz-Signature parameter of presigned URLs.
+	CanonicalSigV4
+
+	// CanonicalOAuth1 produces the normalized request parameters of OAuth
+	// 1.0a, as described by RFC 5849 section 3.4.1.3.2.  It follows the rules
+	// of CanonicalRFC3986, and omits the oauth_signature parameter.
+	CanonicalOAuth1
+)
+
+// excludedParams maps each CanonicalMode to the parameter it omits, if any.
+var excludedParams = map[CanonicalMode]string{
+	CanonicalSigV4:  "X-Amz-Signature",
+	CanonicalOAuth1: "oauth_signature",
+}
+
+// Canonical returns the canonical query string encoding of v used when
+// signing requests, using the rules selected by mode.
+//
+// Canonical accepts the same input and uses the same encoding rules as Values,
+// and then encodes the resulting parameters as EncodeCanonical does.  Unlike
+// url.Values.Encode, the result can be used directly as the query string that
+// is signed.
+func Canonical(v interface{}, mode CanonicalMode) (string, error) {
+	return new(Config).Canonical(v, mode)
+}
+
+// Canonical returns the canonical query string encoding of v, as described
+// by the package level Canonical function, using the options in c.
+func (c *Config) Canonical(v interface{}, mode CanonicalMode) (string, error) {
+	values, err := c.Values(v)
+	if err != nil {
+		return "", err
+	}
+	return EncodeCanonical(values, mode), nil
+}
+
+// EncodeCanonical encodes values into a canonical query string using the
+// rules selected by mode.  It may be used to encode the output of Values
+// after adding any parameters that are not part of a struct, such as those
+// added by the signing process.
+func EncodeCanonical(values url.Values, mode CanonicalMode) string {
+	type param struct {
+		key, value string
+	}
+
+	excluded := excludedParams[mode]
+	var params []param
+	for k, vs := range values {
+		if excluded != "" && k == excluded {
+			continue
+		}
+		key := escapeRFC3986(k)
+		for _, v := range vs {
+			params = append(params, param{key, escapeRFC3986(v)})
+		}
+	}
+
+	sort.Slice(params, func(i, j int) bool {
+		if params[i].key != params[j].key {
+			return params[i].key < params[j].key
+		}
+		return params[i].value < params[j].value
+	})
+
+	var b strings.Builder
+	for i, p := range params {
+		if i > 0 {
+			b.WriteByte('&')
+		}
+		b.WriteString(p.key)
+		b.WriteByte('=')
+		b.WriteString(p.value)
+	}
+	return b.String()
+}
+
+// escapeRFC3986 returns s with every byte other than the unreserved
+// characters of RFC 3986 percent-encoded.
+func escapeRFC3986(s string) string {
+	return percentEscape(s, func(s string, i int) bool {
+		return isUnreserved(s[i])
+	})
+}
@@ -0,0 +1,219 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package query
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// A Param describes a URL parameter that Values encodes from a struct field.
+type Param struct {
+	// Key is the URL parameter name, including the names of any enclosing
+	// structs, such as "user[addr][city]".  It does not include suffixes
+	// added to each value of a slice, such as "[]" for the "brackets" option.
+	Key string
+
+	// Path lists the URL parameter names of the field and of the struct
+	// fields enclosing it, such as ["user" "addr" "city"].  It may differ
+	// from Key when an enclosing struct uses the exploded "form" style.
+	Path []string
+
+	// Index is the index sequence of the field within the described struct
+	// type, for use with reflect.Value.FieldByIndex.
+	Index []int
+
+	// Type is the declared Go type of the field.
+	Type reflect.Type
+
+	// OmitEmpty reports whether the field is omitted when empty.
+	OmitEmpty bool
+
+	// Int reports whether boolean values are encoded as "1" or "0".
+	Int bool
+
+	// SliceStyle describes how slice and array values are encoded.  It is one
+	// of "repeated", "brackets", "numbered", "indexed" or "delimited" for
+	// slices and arrays, and empty for other types.
+	SliceStyle string
+
+	// IndexStart is the index of the first element when SliceStyle is
+	// "indexed".
+	IndexStart int
+
+	// OmitEmptyElem reports whether empty elements of slices and arrays are
+	// dropped.
+	OmitEmptyElem bool
+
+	// KeepEmpty reports whether empty, non-nil slices and arrays are encoded
+	// as a single empty value.
+	KeepEmpty bool
+
+	// Delimiter is the string joining slice and array elements when
+	// SliceStyle is "delimited".
+	Delimiter string
+
+	// Escape is the policy for elements containing Delimiter given by the
+	// "escape" option: one of "backslash", "percent", "quote" or "error", or
+	// empty if the option is not given.
+	Escape string
+
+	// TimeFormat describes how time.Time values are encoded.  It is one of
+	// "rfc3339", "unix", "unixmilli", "unixnano", "httpdate" or "layout" for
+	// times and slices of times, and empty for other types.
+	TimeFormat string
+
+	// Layout is the time.Format layout used when TimeFormat is "layout".
+	Layout string
+
+	// Style is the OpenAPI parameter style given in the field's options, if
+	// any, and Explode reports whether that style is exploded.
+	Style   string
+	Explode bool
+
+	// Encoder reports whether the field's type implements Encoder, so that
+	// its parameters are determined by its EncodeValues method, which is
+	// passed Key.  It is false for structs with EncodeValues methods
+	// generated by querygen, whose fields are described instead.
+	Encoder bool
+}
+
+// Describe returns a description of each URL parameter that Values encodes
+// from a struct of type t, in the order they are encoded.  t must be a struct
+// type or a pointer to one.
+//
+// Fields of nested structs are described individually, with Key scoped by the
+// enclosing field names, and embedded structs are followed using the same
+// rules as Values.  A field whose parameters are only known when it is
+// encoded is described by a single Param whose Key is the scope of those
+// parameters.  This is the case for maps, interfaces, types implementing
+// Encoder (other than those generated by querygen), structs joined into a
+// single value by an OpenAPI style, and structs that recursively contain their
+// own type.
+func Describe(t reflect.Type) ([]Param, error) {
+	return new(Config).Describe(t)
+}
+
+// Describe returns a description of the URL parameters encoded from a struct
+// of type t, as described by the package level Describe function, using the
+// options in c.
+func (c *Config) Describe(t reflect.Type) ([]Param, error) {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("query: Describe() expects struct type. Got %v", t)
+	}
+
+	var params []Param
+	err := c.describeStruct(&params, t, "", nil, nil, map[reflect.Type]bool{})
+	return params, err
+}
+
+// describeStruct appends the parameters of the fields of the struct type t,
+// within scope, to params.  path and index are those of the enclosing field,
+// and active holds the struct types currently being described.
+func (c *Config) describeStruct(params *[]Param, t reflect.Type, scope string, path []string, index []int, active map[reflect.Type]bool) error {
+	fields := cachedTypeFields(t, "url")
+	if c.DisallowConflicts && len(fields.conflicts) > 0 {
+		return fmt.Errorf("query: conflicting fields for parameter %q in %v", fields.conflicts[0], t)
+	}
+	if c.Strict && fields.tagErr != nil {
+		return fields.tagErr
+	}
+
+	active[t] = true
+	defer delete(active, t)
+
+	for _, f := range fields.list {
+		p := Param{
+			Key:       scopedName(scope, f.name),
+			Path:      append(path[:len(path):len(path)], f.name),
+			Index:     append(index[:len(index):len(index)], f.index...),
+			Type:      f.sf.Type,
+			OmitEmpty: f.opts.Contains("omitempty"),
+		}
+		if err := c.describeField(params, p, scope, f.opts, f.sf, active); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// describeField appends the parameters of the field described by p to
+// params, following the same rules as reflectField.
+func (c *Config) describeField(params *[]Param, p Param, scope string, opts tagOptions, sf reflect.StructField, active map[reflect.Type]bool) error {
+	// generated encoders are described by their fields, which they encode
+	// exactly as reflection would
+	t := p.Type
+	if t.Kind() == reflect.Interface || t.Implements(encoderType) && !t.Implements(generatedEncoderType) {
+		p.Encoder = t.Kind() != reflect.Interface
+		*params = append(*params, p)
+		return nil
+	}
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	p.Style, p.Explode = openAPIStyle(opts)
+
+	base := t
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		if del := sliceDelimiter(opts, sf); del != "" {
+			p.SliceStyle, p.Delimiter = "delimited", del
+			p.Escape, _ = escapePolicy(opts)
+		} else if opts.Contains("brackets") {
+			p.SliceStyle = "brackets"
+		} else if opts.Contains("numbered") {
+			p.SliceStyle = "numbered"
+		} else if start, ok := indexStart(opts); ok {
+			p.SliceStyle, p.IndexStart = "indexed", start
+		} else {
+			p.SliceStyle = "repeated"
+		}
+		p.OmitEmptyElem = opts.Contains("omitemptyelem")
+		p.KeepEmpty = opts.Contains("keepempty")
+
+		base = t.Elem()
+		for base.Kind() == reflect.Ptr {
+			base = base.Elem()
+		}
+	} else if t != timeType && (t.Kind() == reflect.Struct || t.Kind() == reflect.Map) {
+		switch {
+		case t.Kind() == reflect.Map || active[t]:
+		case p.Style == "" || p.Style == "deepObject":
+			return c.describeStruct(params, t, p.Key, p.Path, p.Index, active)
+		case p.Explode:
+			// exploded objects are flattened into the enclosing scope
+			return c.describeStruct(params, t, scope, p.Path, p.Index, active)
+		}
+		*params = append(*params, p)
+		return nil
+	}
+
+	p.Int = base.Kind() == reflect.Bool && opts.Contains("int")
+	if base == timeType {
+		p.TimeFormat, p.Layout = timeFormat(opts, sf)
+	}
+
+	*params = append(*params, p)
+	return nil
+}
+
+// timeFormat returns the name of the format used to encode time.Time values
+// with the given options, as applied by valueString, and the layout if the
+// "layout" tag is used.
+func timeFormat(opts tagOptions, sf reflect.StructField) (format, layout string) {
+	for _, f := range []string{"unix", "unixmilli", "unixnano", "httpdate"} {
+		if opts.Contains(f) {
+			return f, ""
+		}
+	}
+	if layout := sf.Tag.Get("layout"); layout != "" {
+		return "layout", layout
+	}
+	return "rfc3339", ""
+}
@@ -0,0 +1,179 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package query
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDescribe(t *testing.T) {
+	type Address struct {
+		City string `url:"city,omitempty"`
+	}
+	type User struct {
+		Name string   `url:"name"`
+		Addr *Address `url:"addr"`
+	}
+	type Point struct {
+		X, Y int
+	}
+	type Node struct {
+		Name string `url:"name"`
+		Next *Node  `url:"next"`
+	}
+	type Embedded struct {
+		Page  int `url:"page"`
+		Limit int `url:"limit"`
+	}
+	type Options struct {
+		Query   string            `url:"q"`
+		Flags   []bool            `url:"flag,int" del:"!"`
+		Tags    []string          `url:"tag,brackets,omitemptyelem"`
+		IDs     []int             `url:"id,numbered,keepempty"`
+		Colors  []string          `url:"color,pipeDelimited"`
+		Since   time.Time         `url:"since,unix"`
+		Days    []*time.Time      `layout:"2006-01-02"`
+		User    User              `url:"user"`
+		Origin  Point             `url:"origin,form"`
+		Size    Point             `url:"size,form,noexplode"`
+		Filter  map[string]string `url:"filter,deepObject"`
+		Extra   interface{}       `url:"extra"`
+		Custom  customEncodedInt  `url:"custom"`
+		Tree    Node              `url:"tree"`
+		Token   string            `header:"X-Token"`
+		Ignored string            `url:"-"`
+		Page    int               `url:"page"`
+		Embedded
+	}
+
+	got, err := Describe(reflect.TypeOf(&Options{}))
+	if err != nil {
+		t.Fatalf("Describe returned error: %v", err)
+	}
+
+	want := []Param{
+		{Key: "q", Path: []string{"q"}, Index: []int{0}, Type: reflect.TypeOf("")},
+		{
+			Key: "flag", Path: []string{"flag"}, Index: []int{1}, Type: reflect.TypeOf([]bool{}),
+			Int: true, SliceStyle: "delimited", Delimiter: "!",
+		},
+		{Key: "tag", Path: []string{"tag"}, Index: []int{2}, Type: reflect.TypeOf([]string{}), SliceStyle: "brackets", OmitEmptyElem: true},
+		{Key: "id", Path: []string{"id"}, Index: []int{3}, Type: reflect.TypeOf([]int{}), SliceStyle: "numbered", KeepEmpty: true},
+		{
+			Key: "color", Path: []string{"color"}, Index: []int{4}, Type: reflect.TypeOf([]string{}),
+			SliceStyle: "delimited", Delimiter: "|", Style: "pipeDelimited",
+		},
+		{Key: "since", Path: []string{"since"}, Index: []int{5}, Type: timeType, TimeFormat: "unix"},
+		{
+			Key: "Days", Path: []string{"Days"}, Index: []int{6}, Type: reflect.TypeOf([]*time.Time{}),
+			SliceStyle: "repeated", TimeFormat: "layout", Layout: "2006-01-02",
+		},
+		{Key: "user[name]", Path: []string{"user", "name"}, Index: []int{7, 0}, Type: reflect.TypeOf("")},
+		{
+			Key: "user[addr][city]", Path: []string{"user", "addr", "city"}, Index: []int{7, 1, 0},
+			Type: reflect.TypeOf(""), OmitEmpty: true,
+		},
+		{Key: "X", Path: []string{"origin", "X"}, Index: []int{8, 0}, Type: reflect.TypeOf(0)},
+		{Key: "Y", Path: []string{"origin", "Y"}, Index: []int{8, 1}, Type: reflect.TypeOf(0)},
+		{Key: "size", Path: []string{"size"}, Index: []int{9}, Type: reflect.TypeOf(Point{}), Style: "form"},
+		{
+			Key: "filter", Path: []string{"filter"}, Index: []int{10}, Type: reflect.TypeOf(map[string]string{}),
+			Style: "deepObject", Explode: true,
+		},
+		{Key: "extra", Path: []string{"extra"}, Index: []int{11}, Type: reflect.TypeOf(new(interface{})).Elem()},
+		{Key: "custom", Path: []string{"custom"}, Index: []int{12}, Type: reflect.TypeOf(customEncodedInt(0)), Encoder: true},
+		{Key: "tree[name]", Path: []string{"tree", "name"}, Index: []int{13, 0}, Type: reflect.TypeOf("")},
+		{Key: "tree[next]", Path: []string{"tree", "next"}, Index: []int{13, 1}, Type: reflect.TypeOf(&Node{})},
+		{Key: "page", Path: []string{"page"}, Index: []int{16}, Type: reflect.TypeOf(0)},
+		{Key: "limit", Path: []string{"limit"}, Index: []int{17, 1}, Type: reflect.TypeOf(0)},
+	}
+
+	typeEqual := cmp.Comparer(func(x, y reflect.Type) bool { return x == y })
+	if diff := cmp.Diff(want, got, typeEqual); diff != "" {
+		t.Errorf("Describe mismatch:\n%s", diff)
+	}
+
+	// check that each described field is found by its index
+	v := reflect.ValueOf(Options{User: User{Addr: &Address{}}})
+	for _, p := range got {
+		if ft := v.FieldByIndex(p.Index).Type(); ft != p.Type {
+			t.Errorf("field %v of Options has type %v, want %v", p.Index, ft, p.Type)
+		}
+	}
+}
+
+func TestDescribe_Generated(t *testing.T) {
+	type Options struct {
+		N customEncodedStruct `url:"n"`
+		C colorStruct         `url:"c"`
+	}
+
+	got, err := Describe(reflect.TypeOf(Options{}))
+	if err != nil {
+		t.Fatalf("Describe returned error: %v", err)
+	}
+
+	// generated encoders are described by their fields
+	want := []Param{
+		{Key: "n[A]", Path: []string{"n", "A"}, Index: []int{0, 0}, Type: reflect.TypeOf("")},
+		{Key: "n[b]", Path: []string{"n", "b"}, Index: []int{0, 1}, Type: reflect.TypeOf(0)},
+		{Key: "c", Path: []string{"c"}, Index: []int{1}, Type: reflect.TypeOf(colorStruct{}), Encoder: true},
+	}
+	typeEqual := cmp.Comparer(func(x, y reflect.Type) bool { return x == y })
+	if diff := cmp.Diff(want, got, typeEqual); diff != "" {
+		t.Errorf("Describe mismatch:\n%s", diff)
+	}
+}
+
+func TestDescribe_Indexed(t *testing.T) {
+	type Options struct {
+		IDs   []int     `url:"id,indexed"`
+		Codes [2]string `url:"code,indexed=1"`
+	}
+
+	got, err := Describe(reflect.TypeOf(Options{}))
+	if err != nil {
+		t.Fatalf("Describe returned error: %v", err)
+	}
+
+	want := []Param{
+		{Key: "id", Path: []string{"id"}, Index: []int{0}, Type: reflect.TypeOf([]int{}), SliceStyle: "indexed"},
+		{Key: "code", Path: []string{"code"}, Index: []int{1}, Type: reflect.TypeOf([2]string{}), SliceStyle: "indexed", IndexStart: 1},
+	}
+
+	typeEqual := cmp.Comparer(func(x, y reflect.Type) bool { return x == y })
+	if diff := cmp.Diff(want, got, typeEqual); diff != "" {
+		t.Errorf("Describe mismatch:\n%s", diff)
+	}
+}
+
+func TestDescribe_Errors(t *testing.T) {
+	for _, typ := range []reflect.Type{nil, reflect.TypeOf(0), reflect.TypeOf(map[string]int{})} {
+		if _, err := Describe(typ); err == nil {
+			t.Errorf("Describe(%v) did not return an error", typ)
+		}
+	}
+
+	type A struct {
+		Name string
+	}
+	type B struct {
+		Name string
+	}
+	type S struct {
+		A
+		B
+	}
+	if _, err := (&Config{DisallowConflicts: true}).Describe(reflect.TypeOf(S{})); err == nil {
+		t.Errorf("Describe with conflicting fields did not return an error")
+	}
+	if got, err := Describe(reflect.TypeOf(S{})); err != nil || len(got) != 0 {
+		t.Errorf("Describe(S) = %v, %v, want no parameters", got, err)
+	}
+}
@@ -21,9 +21,13 @@
 package query
 
 import (
+	"encoding"
+	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -33,25 +37,69 @@ var timeType = reflect.TypeOf(time.Time{})
 
 var encoderType = reflect.TypeOf(new(Encoder)).Elem()
 
+var generatedEncoderType = reflect.TypeOf(new(generatedEncoder)).Elem()
+
 // Encoder is an interface implemented by any type that wishes to encode
 // itself into URL values in a non-standard way.
 type Encoder interface {
 	EncodeValues(key string, v *url.Values) error
 }
 
+// generatedEncoder is implemented by types with EncodeValues methods
+// generated by the querygen command, which encode the whole struct exactly as
+// reflectValue would.  Values only uses EncodeValues for a top-level struct
+// that implements generatedEncoder, as other Encoder types may expect to be
+// passed a non-empty key.  Entry points that keep the order of fields, or
+// that write files, always use reflection, which the generated methods do not
+// support.
+type generatedEncoder interface {
+	Encoder
+	QuerygenEncoder()
+}
+
+// EncodeField adds the encoding of a single struct field to v, exactly as
+// Values would encode it when found within scope.  field must be a pointer to
+// the struct field, name is its URL parameter name and tag is its full struct
+// tag, from which the "url" options and any "del" or "layout" tags are read.
+//
+// EncodeField is intended for EncodeValues methods generated by the querygen
+// command, which use it for fields they cannot encode directly.
+func EncodeField(v *url.Values, scope, name string, field interface{}, tag reflect.StructTag) error {
+	fv := reflect.ValueOf(field)
+	if fv.Kind() != reflect.Ptr || fv.IsNil() {
+		return fmt.Errorf("query: EncodeField() expects a non-nil pointer to a struct field. Got %v", fv.Kind())
+	}
+	if *v == nil {
+		*v = make(url.Values)
+	}
+
+	sv := fv.Elem()
+	_, opts := parseTag(tag.Get("url"))
+	if opts.Contains("omitempty") && isEmptyValue(sv) {
+		return nil
+	}
+	return new(Config).reflectField(*v, sv, scope, name, opts, reflect.StructField{Tag: tag})
+}
+
 // Values returns the url.Values encoding of v.
 //
-// Values expects to be passed a struct, and traverses it recursively using the
-// following encoding rules.
+// Values expects to be passed a struct or a map, and traverses it recursively
+// using the following encoding rules.
 //
 // Each exported struct field is encoded as a URL parameter unless
 //
 //   - the field's tag is "-", or
-//   - the field is empty and its tag specifies the "omitempty" option
+//   - the field is empty and its tag specifies the "omitempty" option, or
+//   - the field has a "path" tag, marking it as a path parameter (see Path), or
+//   - the field has a "header" tag, marking it as an HTTP header (see Header)
 //
 // The empty values are false, 0, any nil pointer or interface value, any array
 // slice, map, or string of length zero, and any type (such as time.Time) that
-// returns true for IsZero().
+// returns true for IsZero().  A struct is also empty if every field that
+// would be encoded from it is empty, so that an omitempty struct field is
+// skipped entirely rather than encoding its fields' empty values.  Structs
+// implementing Encoder are only empty if they return true for IsZero(),
+// unless their EncodeValues method was generated by the querygen command.
 //
 // The URL parameter name defaults to the struct field name but can be
 // specified in the struct field's tag value.  The "url" key in the struct
@@ -83,9 +131,10 @@ type Encoder interface {
 // "unix" option signals that the field should be encoded as a Unix time (see
 // time.Unix()).  The "unixmilli" and "unixnano" options will encode the number
 // of milliseconds and nanoseconds, respectively, since January 1, 1970 (see
-// time.UnixNano()).  Including the "layout" struct tag (separate from the
-// "url" tag) will use the value of the "layout" tag as a layout passed to
-// time.Format.  For example:
+// time.UnixNano()).  The "httpdate" option encodes the time in the HTTP-date
+// format used by HTTP headers (see http.TimeFormat).  Including the "layout"
+// struct tag (separate from the "url" tag) will use the value of the "layout"
+// tag as a layout passed to time.Format.  For example:
 //
 //	// Encode a time.Time as YYYY-MM-DD
 //	Field time.Time `layout:"2006-01-02"`
@@ -94,35 +143,190 @@ type Encoder interface {
 // same name.  Including the "comma" option signals that the field should be
 // encoded as a single comma-delimited value.  Including the "space" option
 // similarly encodes the value as a single space-delimited string. Including
-// the "semicolon" option will encode the value as a semicolon-delimited string.
-// Including the "brackets" option signals that the multiple URL values should
-// have "[]" appended to the value name. "numbered" will append a number to
-// the end of each incidence of the value name, example:
-// name0=value0&name1=value1, etc.  Including the "del" struct tag (separate
-// from the "url" tag) will use the value of the "del" tag as the delimiter.
-// For example:
+// the "semicolon" option will encode the value as a semicolon-delimited string,
+// and the "pipe" option as a string delimited by "|".  Including the
+// "brackets" option signals that the multiple URL values should have "[]"
+// appended to the value name. "numbered" will append a number to the end of
+// each incidence of the value name, example: name0=value0&name1=value1, etc.
+// "indexed" will append a bracketed index instead, starting from zero,
+// example: name[0]=value0&name[1]=value1, or starting from another index
+// given as its value, such as "indexed=1".  Including the "del" option with
+// a value, such as "del=::", will use that value as the delimiter.  As
+// options are separated by commas, such a delimiter cannot contain a comma.
+// Including the "del" struct tag (separate from the "url" tag) will likewise
+// use the value of the "del" tag as the delimiter, unless the "url" tag
+// includes another slice option.  For example:
 //
 //	// Encode a slice of bools as ints ("1" for true, "0" for false),
 //	// separated by exclamation points "!".
+//	Field []bool `url:",int,del=!"`
 //	Field []bool `url:",int" del:"!"`
 //
+// Elements containing the delimiter are encoded as they are, so that they
+// cannot be told apart from multiple elements.  Including the "escape" option
+// signals that each backslash and each occurrence of the delimiter within an
+// element should be preceded by a backslash.  Other policies are given as the
+// option's value:
+//
+//   - "escape=backslash" is the same as "escape"
+//   - "escape=percent" percent-encodes each "%" and each occurrence of the
+//     delimiter within an element, before the value is itself URL encoded
+//   - "escape=quote" encloses elements containing the delimiter or a double
+//     quote in double quotes, doubling any double quotes within them, as in
+//     CSV files
+//   - "escape=error" causes Values to return an error if an element contains
+//     the delimiter
+//
+// For example:
+//
+//	// Encode []string{"a|b", "c"} as "a\|b|c".
+//	Field []string `url:",pipe,escape"`
+//
+//	// Encode []string{"a,b", "c"} as "a%2Cb,c", which is further encoded
+//	// as "a%252Cb%2Cc" in the query string.
+//	Field []string `url:",comma,escape=percent"`
+//
+//	// Encode []string{"a,b", "c"} as "\"a,b\",c".
+//	Field []string `url:",comma,escape=quote"`
+//
+// Including the "omitemptyelem" option drops the empty elements of a slice or
+// array, using the same definition of empty as "omitempty", before encoding
+// it in any of these ways.  Numbered and indexed elements are numbered
+// consecutively after empty elements are dropped.  For example:
+//
+//	// Encode []string{"", "a", ""} as "tag=a" rather than "tag=&tag=a&tag=".
+//	Field []string `url:"tag,omitemptyelem"`
+//
+// Slices and arrays with no elements to encode are skipped entirely, unless
+// the "keepempty" option is included, which encodes a non-nil slice, or an
+// array, with no elements as a single empty value, such as "tag=" (or "tag[]="
+// with the "brackets" option).  Nil slices are still skipped, so that they
+// can be distinguished from empty slices, and both are skipped if the
+// "omitempty" option is also included.  For example:
+//
+//	// Encode []string{} as "tag=", but skip a nil slice.
+//	Field []string `url:"tag,keepempty"`
+//
+// Slices, arrays, structs and maps may instead be encoded using one of the
+// query parameter styles defined by the OpenAPI 3 specification by including
+// the "form", "spaceDelimited", "pipeDelimited" or "deepObject" option.  The
+// "explode" and "noexplode" options override the default explode setting for
+// the style, which is true for "form" and "deepObject" and false otherwise.
+// For a field named "color", these encode as follows (shown before escaping):
+//
+//	options          []string{"blue", "black"}  struct{ R, G int }{100, 200}
+//	form,noexplode   color=blue,black           color=R,100,G,200
+//	form             color=blue&color=black     R=100&G=200
+//	spaceDelimited   color=blue black           color=R 100 G 200
+//	pipeDelimited    color=blue|black           color=R|100|G|200
+//	deepObject       color=blue&color=black     color[R]=100&color[G]=200
+//
+// Exploded "form" objects are flattened into the enclosing scope, and
+// "spaceDelimited" and "pipeDelimited" parameters with the "explode" option
+// encode the same as "form".  Other values are not affected by these options.
+//
 // Anonymous struct fields are usually encoded as if their inner exported
 // fields were fields in the outer struct, subject to the standard Go
 // visibility rules.  An anonymous struct field with a name given in its URL
-// tag is treated as having that name, rather than being anonymous.
+// tag is treated as having that name, rather than being anonymous.  Fields of
+// a nil anonymous struct pointer are skipped.  A named struct or struct
+// pointer field with the "inline" option is treated as if it were anonymous,
+// flattening its fields into the outer struct:
+//
+//	// Fields of Paging appear as if they were fields of the outer struct.
+//	Paging Paging `url:",inline"`
+//
+// The "prefix" and "suffix" options likewise flatten an embedded or named
+// struct field, adding the given prefix or suffix to the URL parameter names
+// of its fields.  These compose across nested structs, so that
+//
+//	// Fields of Filter appear with names like "filter_status".
+//	Filter Filter `url:",prefix=filter_"`
+//
+// encodes the "owner" field of a struct within Filter that has the option
+// "prefix=created_" as "filter_created_owner".  The fields of a flattened
+// struct are encoded individually, so the "omitempty" option has no effect on
+// the struct field itself.
+//
+// When several fields of the outer and embedded structs have the same URL
+// parameter name, the same rules as the encoding/json package are used to
+// pick which of them is encoded:
+//
+//   - Of those fields, if any are less nested than the others, only the least
+//     nested fields are considered.
+//   - Of the remaining fields, if any have their name given in the URL tag,
+//     only those fields and the other fields of the same struct are
+//     considered.
+//   - If a single field or several fields of the same struct remain, they are
+//     encoded.  Otherwise the name is ambiguous and none of them are encoded.
 //
-// Non-nil pointer values are encoded as the value pointed to.
+// Use a Config with DisallowConflicts set to report an error instead of
+// silently dropping fields.
+//
+// Non-nil pointer values are encoded as the value pointed to.  Likewise,
+// non-nil interface values are encoded as the dynamic value they hold, using
+// all of the rules above.  A nil interface value is encoded as an empty
+// string, the same as a nil pointer.
 //
 // Nested structs have their fields processed recursively and are encoded
 // including parent fields in value names for scoping. For example,
 //
 //	"user[name]=acme&user[addr][postcode]=1234&user[addr][city]=SFO"
 //
+// Maps are encoded like structs, with each entry treated as a field whose URL
+// parameter name is the map key.  The keys of a map passed directly to Values
+// must be strings or implement encoding.TextMarshaler, while other keys of
+// nested maps are formatted using fmt.Sprint.  Entries are encoded in sorted
+// key order, and map values are encoded using the same rules as struct fields
+// without any tag options, so nested maps and structs are scoped as above and
+// slices are encoded as multiple URL values of the same name.  Map fields of
+// structs are scoped in the same way, so a nil or empty map field adds no
+// parameters; earlier versions of this package encoded map fields using
+// fmt.Sprint instead, such as "m=map[]".  A map passed directly to Values is
+// encoded without scoping, so that
+//
+//	map[string]interface{}{"q": "foo", "filter": map[string]int{"page": 2}}
+//
+// encodes as "filter[page]=2&q=foo".
+//
 // All other values are encoded using their default string representation.
 //
-// Multiple fields that encode to the same URL parameter name will be included
-// as multiple URL values of the same name.
+// Multiple fields of the same struct that encode to the same URL parameter
+// name will be included as multiple URL values of the same name.
+//
+// A struct with an EncodeValues method generated by the querygen command is
+// encoded by calling that method with an empty key, which produces the same
+// values without reflection.  Other structs are always encoded by the rules
+// above, even if they implement Encoder, whose EncodeValues method is only
+// used when the struct is a field of another.
 func Values(v interface{}) (url.Values, error) {
+	return new(Config).Values(v)
+}
+
+// A Config controls how values are encoded.  The zero Config encodes values
+// exactly as the Values function does.
+type Config struct {
+	// DisallowConflicts causes encoding to fail if a struct has fields that
+	// are dropped because they conflict with another field of the same
+	// name, rather than silently omitting them.
+	DisallowConflicts bool
+
+	// Replace causes AppendValues and AddToURL to replace any existing
+	// values for the keys that are encoded, rather than adding to them.
+	Replace bool
+
+	// Strict causes encoding to fail if a struct field's url tag has an
+	// unknown option, conflicting options such as "comma" and "brackets",
+	// the "int" option on a field that does not hold booleans, or a time
+	// format option such as "unix" on a field that does not hold times, or
+	// the "omitempty" option on a struct field that is flattened.  Tags are
+	// checked once per struct type.
+	Strict bool
+}
+
+// Values returns the url.Values encoding of v, as described by the package
+// level Values function, using the options in c.
+func (c *Config) Values(v interface{}) (url.Values, error) {
 	values := make(url.Values)
 
 	if v == nil {
@@ -137,137 +341,314 @@ func Values(v interface{}) (url.Values, error) {
 		val = val.Elem()
 	}
 
-	if val.Kind() != reflect.Struct {
-		return nil, fmt.Errorf("query: Values() expects struct input. Got %v", val.Kind())
+	var err error
+	switch val.Kind() {
+	case reflect.Struct:
+		if m, ok := v.(generatedEncoder); ok && !c.Strict && !c.DisallowConflicts {
+			// the generated method does not check the struct's tags
+			err = m.EncodeValues("", &values)
+		} else {
+			err = c.reflectValue(values, val, "")
+		}
+	case reflect.Map:
+		err = c.reflectMapInput(values, val, "")
+	default:
+		return nil, fmt.Errorf("query: Values() expects struct or map input. Got %v", val.Kind())
 	}
-
-	err := reflectValue(values, val, "")
 	return values, err
 }
 
-// reflectValue populates the values parameter from the struct fields in val.
-// Embedded structs are followed recursively (using the rules defined in the
-// Values function documentation) breadth-first.
-func reflectValue(values url.Values, val reflect.Value, scope string) error {
-	var embedded []reflect.Value
+// AppendValues adds the url.Values encoding of v to dst.  Encoded values are
+// added after any values already in dst for the same key.
+func AppendValues(dst url.Values, v interface{}) error {
+	return new(Config).AppendValues(dst, v)
+}
+
+// AppendValues adds the url.Values encoding of v to dst, using the options in
+// c.  If c.Replace is set, the encoded values replace any values already in
+// dst for the same key.
+func (c *Config) AppendValues(dst url.Values, v interface{}) error {
+	if dst == nil {
+		return errors.New("query: AppendValues() called with nil url.Values")
+	}
+
+	values, err := c.Values(v)
+	if err != nil {
+		return err
+	}
+
+	for k, vs := range values {
+		if c.Replace {
+			dst[k] = vs
+		} else {
+			dst[k] = append(dst[k], vs...)
+		}
+	}
+	return nil
+}
+
+// AddToURL adds the url.Values encoding of v to the query string of u,
+// keeping any parameters already present.  The query string is re-encoded
+// using url.Values.Encode, so parameters are sorted by key.
+func AddToURL(u *url.URL, v interface{}) error {
+	return new(Config).AddToURL(u, v)
+}
+
+// AddToURL adds the url.Values encoding of v to the query string of u, using
+// the options in c.  If c.Replace is set, the encoded values replace any
+// parameters already present in u with the same key.
+func (c *Config) AddToURL(u *url.URL, v interface{}) error {
+	if u == nil {
+		return errors.New("query: AddToURL() called with nil *url.URL")
+	}
+
+	q := u.Query()
+	if err := c.AppendValues(q, v); err != nil {
+		return err
+	}
+	u.RawQuery = q.Encode()
+	return nil
+}
 
+// reflectValue populates the values parameter from the struct fields in val.
+// Embedded structs are followed (using the rules defined in the Values
+// function documentation) breadth-first.
+func (c *Config) reflectValue(values valueAdder, val reflect.Value, scope string) error {
 	typ := val.Type()
-	for i := 0; i < typ.NumField(); i++ {
-		sf := typ.Field(i)
-		if sf.PkgPath != "" && !sf.Anonymous { // unexported
+	fields := cachedTypeFields(typ, "url")
+	if c.DisallowConflicts && len(fields.conflicts) > 0 {
+		return fmt.Errorf("query: conflicting fields for parameter %q in %v", fields.conflicts[0], typ)
+	}
+	if c.Strict && fields.tagErr != nil {
+		return fields.tagErr
+	}
+
+	for _, f := range fields.list {
+		sv, ok := fieldByIndex(val, f.index)
+		if !ok {
+			// field of a nil embedded struct pointer
 			continue
 		}
 
-		sv := val.Field(i)
-		tag := sf.Tag.Get("url")
-		if tag == "-" {
+		if f.opts.Contains("omitempty") && isEmptyValue(sv) {
 			continue
 		}
-		name, opts := parseTag(tag)
 
-		if name == "" {
-			if sf.Anonymous {
-				v := reflect.Indirect(sv)
-				if v.IsValid() && v.Kind() == reflect.Struct {
-					// save embedded struct for later processing
-					embedded = append(embedded, v)
-					continue
-				}
-			}
+		if err := c.reflectField(values, sv, scope, f.name, f.opts, f.sf); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
 
-			name = sf.Name
+// reflectField populates the values parameter with the encoding of the
+// single field value sv, using name within scope as its URL parameter name.
+func (c *Config) reflectField(values valueAdder, sv reflect.Value, scope, name string, opts tagOptions, sf reflect.StructField) error {
+	if sv.Kind() == reflect.Interface {
+		if sv.IsNil() {
+			values.Add(scopedName(scope, name), "")
+			return nil
 		}
+		// encode the dynamic value held by the interface
+		return c.reflectField(values, sv.Elem(), scope, name, opts, sf)
+	}
 
-		if scope != "" {
-			name = scope + "[" + name + "]"
+	key := scopedName(scope, name)
+
+	if fa, ok := values.(fileAdder); ok {
+		if f, ok := fileValue(sv, key); ok {
+			return fa.addFile(key, f)
 		}
+	}
 
-		if opts.Contains("omitempty") && isEmptyValue(sv) {
-			continue
+	// generated encoders do not support OpenAPI styles, so their fields are
+	// encoded by reflection instead, as if they were not generated
+	style, _ := openAPIStyle(opts)
+	if sv.Type().Implements(encoderType) && (style == "" || !sv.Type().Implements(generatedEncoderType)) {
+		// if sv is a nil pointer and the custom encoder is defined on a non-pointer
+		// method receiver, set sv to the zero value of the underlying type
+		if !reflect.Indirect(sv).IsValid() && sv.Type().Elem().Implements(encoderType) {
+			sv = reflect.New(sv.Type().Elem())
 		}
 
-		if sv.Type().Implements(encoderType) {
-			// if sv is a nil pointer and the custom encoder is defined on a non-pointer
-			// method receiver, set sv to the zero value of the underlying type
-			if !reflect.Indirect(sv).IsValid() && sv.Type().Elem().Implements(encoderType) {
-				sv = reflect.New(sv.Type().Elem())
-			}
+		m := sv.Interface().(Encoder)
+		if uv, ok := values.(url.Values); ok {
+			return m.EncodeValues(key, &uv)
+		}
 
-			m := sv.Interface().(Encoder)
-			if err := m.EncodeValues(name, &values); err != nil {
-				return err
+		// encode into a temporary url.Values and add its values in
+		// sorted key order
+		uv := make(url.Values)
+		if err := m.EncodeValues(key, &uv); err != nil {
+			return err
+		}
+		keys := make([]string, 0, len(uv))
+		for k := range uv {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			for _, v := range uv[k] {
+				values.Add(k, v)
 			}
-			continue
 		}
+		return nil
+	}
 
-		// recursively dereference pointers. break on nil pointers
-		for sv.Kind() == reflect.Ptr {
-			if sv.IsNil() {
-				break
-			}
-			sv = sv.Elem()
+	// recursively dereference pointers. break on nil pointers
+	for sv.Kind() == reflect.Ptr {
+		if sv.IsNil() {
+			break
 		}
+		sv = sv.Elem()
+	}
 
-		if sv.Kind() == reflect.Slice || sv.Kind() == reflect.Array {
-			if sv.Len() == 0 {
-				// skip if slice or array is empty
-				continue
-			}
+	if sv.Kind() == reflect.Interface {
+		return c.reflectField(values, sv, scope, name, opts, sf)
+	}
 
-			var del string
-			if opts.Contains("comma") {
-				del = ","
-			} else if opts.Contains("space") {
-				del = " "
-			} else if opts.Contains("semicolon") {
-				del = ";"
-			} else if opts.Contains("brackets") {
-				name = name + "[]"
-			} else {
-				del = sf.Tag.Get("del")
+	if sv.Kind() == reflect.Slice || sv.Kind() == reflect.Array {
+		del := sliceDelimiter(opts, sf)
+		if del == "" && opts.Contains("brackets") {
+			key = key + "[]"
+		}
+
+		elems := sliceElems(sv, opts)
+		if len(elems) == 0 {
+			// skip if slice or array is empty, unless a non-nil empty slice
+			// is to be encoded as a single empty value
+			if opts.Contains("keepempty") && !(sv.Kind() == reflect.Slice && sv.IsNil()) {
+				values.Add(key, "")
 			}
+			return nil
+		}
 
-			if del != "" {
-				s := new(strings.Builder)
-				first := true
-				for i := 0; i < sv.Len(); i++ {
-					if first {
-						first = false
-					} else {
-						s.WriteString(del)
-					}
-					s.WriteString(valueString(sv.Index(i), opts, sf))
+		if del != "" {
+			s := new(strings.Builder)
+			first := true
+			for _, e := range elems {
+				if first {
+					first = false
+				} else {
+					s.WriteString(del)
+				}
+				elem, err := escapeDelimiter(valueString(e, opts, sf), del, opts)
+				if err != nil {
+					return err
+				}
+				s.WriteString(elem)
+			}
+			values.Add(key, s.String())
+		} else {
+			fa, files := values.(fileAdder)
+			for i, e := range elems {
+				k := key
+				if opts.Contains("numbered") {
+					k = fmt.Sprintf("%s%d", key, i)
+				} else if start, ok := indexStart(opts); ok {
+					k = fmt.Sprintf("%s[%d]", key, start+i)
 				}
-				values.Add(name, s.String())
-			} else {
-				for i := 0; i < sv.Len(); i++ {
-					k := name
-					if opts.Contains("numbered") {
-						k = fmt.Sprintf("%s%d", name, i)
+				if files {
+					if f, ok := fileValue(e, k); ok {
+						if err := fa.addFile(k, f); err != nil {
+							return err
+						}
+						continue
 					}
-					values.Add(k, valueString(sv.Index(i), opts, sf))
 				}
+				values.Add(k, valueString(e, opts, sf))
 			}
-			continue
 		}
+		return nil
+	}
 
-		if sv.Type() == timeType {
-			values.Add(name, valueString(sv, opts, sf))
-			continue
+	if sv.Type() == timeType {
+		values.Add(key, valueString(sv, opts, sf))
+		return nil
+	}
+
+	if sv.Kind() == reflect.Struct || sv.Kind() == reflect.Map {
+		return c.reflectObject(values, sv, scope, name, opts)
+	}
+
+	values.Add(key, valueString(sv, opts, sf))
+	return nil
+}
+
+// sliceElems returns the elements of the slice or array sv that are encoded,
+// which excludes empty elements if opts contains "omitemptyelem".
+func sliceElems(sv reflect.Value, opts tagOptions) []reflect.Value {
+	omit := opts.Contains("omitemptyelem")
+	elems := make([]reflect.Value, 0, sv.Len())
+	for i := 0; i < sv.Len(); i++ {
+		if e := sv.Index(i); !omit || !isEmptyValue(e) {
+			elems = append(elems, e)
 		}
+	}
+	return elems
+}
 
-		if sv.Kind() == reflect.Struct {
-			if err := reflectValue(values, sv, name); err != nil {
-				return err
-			}
-			continue
+// reflectObject populates the values parameter from the fields of a struct
+// or the entries of a map, applying any OpenAPI style given in opts.
+func (c *Config) reflectObject(values valueAdder, sv reflect.Value, scope, name string, opts tagOptions) error {
+	encode := c.reflectValue
+	if sv.Kind() == reflect.Map {
+		encode = c.reflectMap
+	}
+
+	key := scopedName(scope, name)
+	style, explode := openAPIStyle(opts)
+	switch {
+	case style == "" || style == "deepObject":
+		return encode(values, sv, key)
+	case explode:
+		// exploded objects are flattened into the enclosing scope
+		return encode(values, sv, scope)
+	}
+
+	var pairs orderedValues
+	if err := encode(&pairs, sv, ""); err != nil {
+		return err
+	}
+
+	del := styleDelimiters[style]
+	s := new(strings.Builder)
+	for i, p := range pairs {
+		if i > 0 {
+			s.WriteString(del)
 		}
+		s.WriteString(p.key)
+		s.WriteString(del)
+		s.WriteString(p.value)
+	}
+	values.Add(key, s.String())
+	return nil
+}
 
-		values.Add(name, valueString(sv, opts, sf))
+// reflectMap populates the values parameter from the entries of the map val,
+// in sorted key order.
+func (c *Config) reflectMap(values valueAdder, val reflect.Value, scope string) error {
+	type entry struct {
+		key string
+		val reflect.Value
+	}
+
+	entries := make([]entry, 0, val.Len())
+	iter := val.MapRange()
+	for iter.Next() {
+		k, err := mapKeyString(iter.Key())
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{k, iter.Value()})
 	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].key < entries[j].key
+	})
 
-	for _, f := range embedded {
-		if err := reflectValue(values, f, scope); err != nil {
+	for _, e := range entries {
+		if err := c.reflectField(values, e.val, scope, e.key, nil, reflect.StructField{}); err != nil {
 			return err
 		}
 	}
@@ -275,9 +656,221 @@ func reflectValue(values url.Values, val reflect.Value, scope string) error {
 	return nil
 }
 
+// reflectMapInput populates the values parameter from the map val passed
+// directly to one of the encoding functions, whose keys must be strings or
+// implement encoding.TextMarshaler.
+func (c *Config) reflectMapInput(values valueAdder, val reflect.Value, scope string) error {
+	if err := checkMapKeys(val); err != nil {
+		return err
+	}
+	return c.reflectMap(values, val, scope)
+}
+
+// checkMapKeys returns an error if any key of the map val is neither a
+// string nor an encoding.TextMarshaler.
+func checkMapKeys(val reflect.Value) error {
+	for _, k := range val.MapKeys() {
+		if _, ok := k.Interface().(encoding.TextMarshaler); k.Kind() != reflect.String && !ok {
+			return fmt.Errorf("query: unsupported map key type %v", k.Type())
+		}
+	}
+	return nil
+}
+
+// mapKeyString returns the URL parameter name for the map key k.  Keys that
+// are neither strings nor encoding.TextMarshalers are formatted using
+// fmt.Sprint.
+func mapKeyString(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+	if tm, ok := k.Interface().(encoding.TextMarshaler); ok {
+		if k.Kind() == reflect.Ptr && k.IsNil() {
+			return "", nil
+		}
+		b, err := tm.MarshalText()
+		return string(b), err
+	}
+	return fmt.Sprint(k.Interface()), nil
+}
+
+// scopedName returns the URL parameter name for name within scope.
+func scopedName(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "[" + name + "]"
+}
+
+// valueAdder is the destination for encoded values.  It is implemented by
+// url.Values, as well as by orderedValues for callers that need to know the
+// order in which values were encoded.
+type valueAdder interface {
+	Add(key, value string)
+}
+
+// keyValue is a single encoded URL parameter.
+type keyValue struct {
+	key, value string
+}
+
+// orderedValues records encoded URL parameters in the order they are added.
+type orderedValues []keyValue
+
+// Add appends the key and value to o.
+func (o *orderedValues) Add(key, value string) {
+	*o = append(*o, keyValue{key, value})
+}
+
+// sliceDelimiter returns the delimiter used to join the elements of a slice or
+// array field into a single value, or "" if each element is encoded as a
+// separate value.
+func sliceDelimiter(opts tagOptions, sf reflect.StructField) string {
+	switch {
+	case opts.Contains("comma"):
+		return ","
+	case opts.Contains("space"):
+		return " "
+	case opts.Contains("semicolon"):
+		return ";"
+	case opts.Contains("pipe"):
+		return "|"
+	case opts.Contains("brackets"):
+		return ""
+	}
+	if del, ok := opts.Get("del"); ok && del != "" {
+		return del
+	}
+	if _, ok := indexStart(opts); ok {
+		return ""
+	}
+	if style, explode := openAPIStyle(opts); style != "" {
+		if explode {
+			return ""
+		}
+		return styleDelimiters[style]
+	}
+	return sf.Tag.Get("del")
+}
+
+// escapeDelimiter returns the slice element s, to be joined with the
+// delimiter del, escaped using the policy given by the "escape" option in
+// opts.  It returns an error if the policy is "error" and s contains del.
+func escapeDelimiter(s, del string, opts tagOptions) (string, error) {
+	switch policy, _ := escapePolicy(opts); policy {
+	case "":
+		return s, nil
+	case "percent":
+		return strings.NewReplacer("%", "%25", del, percentEscape(del, nil)).Replace(s), nil
+	case "quote":
+		if strings.Contains(s, del) || strings.Contains(s, `"`) {
+			return `"` + strings.Replace(s, `"`, `""`, -1) + `"`, nil
+		}
+		return s, nil
+	case "error":
+		if strings.Contains(s, del) {
+			return "", fmt.Errorf("query: element %q contains the delimiter %q", s, del)
+		}
+		return s, nil
+	}
+	return strings.NewReplacer(`\`, `\\`, del, `\`+del).Replace(s), nil
+}
+
+// escapePolicies are the values of the "escape" option.
+var escapePolicies = []string{"backslash", "percent", "quote", "error"}
+
+// escapePolicy returns the policy given by the "escape" option in opts, which
+// is "backslash" if no value is given, and whether that policy is valid.  The
+// policy is "" if the option is not present.
+func escapePolicy(opts tagOptions) (string, bool) {
+	if opts.Contains("escape") {
+		return "backslash", true
+	}
+	s, ok := opts.Get("escape")
+	if !ok {
+		return "", true
+	}
+	for _, p := range escapePolicies {
+		if s == p {
+			return s, true
+		}
+	}
+	return "backslash", false
+}
+
+// percentEscape returns s with each byte percent-encoded using upper case hex
+// digits, except the bytes s[i] for which keep(s, i) reports true.  A nil
+// keep encodes every byte.
+func percentEscape(s string, keep func(s string, i int) bool) string {
+	const hex = "0123456789ABCDEF"
+
+	b := new(strings.Builder)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if keep != nil && keep(s, i) {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&15])
+	}
+	return b.String()
+}
+
+// indexStart returns the index of the first element of a slice or array
+// encoded with the "indexed" option, and whether the option is present.  The
+// index is given as the option's value, such as "indexed=1", and defaults to
+// zero.
+func indexStart(opts tagOptions) (int, bool) {
+	if opts.Contains("indexed") {
+		return 0, true
+	}
+	s, ok := opts.Get("indexed")
+	if !ok {
+		return 0, false
+	}
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 {
+		return n, true
+	}
+	return 0, true
+}
+
+// styleDelimiters maps OpenAPI parameter styles to the delimiter used when
+// they are not exploded.
+var styleDelimiters = map[string]string{
+	"form":           ",",
+	"spaceDelimited": " ",
+	"pipeDelimited":  "|",
+}
+
+// openAPIStyle returns the OpenAPI parameter style named in opts, if any, and
+// whether the parameter is exploded.  The "explode" and "noexplode" options
+// override the default for the style, which is to explode only "form" and
+// "deepObject" parameters.
+func openAPIStyle(opts tagOptions) (style string, explode bool) {
+	for _, s := range []string{"form", "spaceDelimited", "pipeDelimited", "deepObject"} {
+		if opts.Contains(s) {
+			style = s
+			break
+		}
+	}
+	if style == "" {
+		return "", false
+	}
+
+	explode = style == "form" || style == "deepObject"
+	if opts.Contains("explode") {
+		explode = true
+	} else if opts.Contains("noexplode") {
+		explode = false
+	}
+	return style, explode
+}
+
 // valueString returns the string representation of a value.
 func valueString(v reflect.Value, opts tagOptions, sf reflect.StructField) string {
-	for v.Kind() == reflect.Ptr {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
 		if v.IsNil() {
 			return ""
 		}
@@ -302,6 +895,9 @@ func valueString(v reflect.Value, opts tagOptions, sf reflect.StructField) strin
 		if opts.Contains("unixnano") {
 			return strconv.FormatInt(t.UnixNano(), 10)
 		}
+		if opts.Contains("httpdate") {
+			return t.UTC().Format(http.TimeFormat)
+		}
 		if layout := sf.Tag.Get("layout"); layout != "" {
 			return t.Format(layout)
 		}
@@ -337,9 +933,30 @@ func isEmptyValue(v reflect.Value) bool {
 		return z.IsZero()
 	}
 
+	// generated encoders encode the same fields as reflection
+	if v.Kind() == reflect.Struct && (!v.Type().Implements(encoderType) || v.Type().Implements(generatedEncoderType)) {
+		return isEmptyStruct(v)
+	}
+
 	return false
 }
 
+// isEmptyStruct reports whether all of the fields of the struct v that Values
+// would encode are empty.  Structs without any such fields are not empty.
+func isEmptyStruct(v reflect.Value) bool {
+	fields := cachedTypeFields(v.Type(), "url").list
+	if len(fields) == 0 {
+		return false
+	}
+	for _, f := range fields {
+		sv, ok := fieldByIndex(v, f.index)
+		if ok && !isEmptyValue(sv) {
+			return false
+		}
+	}
+	return true
+}
+
 // tagOptions is the string following a comma in a struct field's "url" tag, or
 // the empty string. It does not include the leading comma.
 type tagOptions []string
@@ -351,6 +968,153 @@ func parseTag(tag string) (string, tagOptions) {
 	return s[0], s[1:]
 }
 
+// knownOptions are the options recognized in struct tags.
+var knownOptions = map[string]bool{
+	"omitempty":      true,
+	"int":            true,
+	"unix":           true,
+	"unixmilli":      true,
+	"unixnano":       true,
+	"httpdate":       true,
+	"comma":          true,
+	"space":          true,
+	"semicolon":      true,
+	"brackets":       true,
+	"numbered":       true,
+	"form":           true,
+	"spaceDelimited": true,
+	"pipeDelimited":  true,
+	"deepObject":     true,
+	"explode":        true,
+	"noexplode":      true,
+	"inline":         true,
+	"omitemptyelem":  true,
+	"keepempty":      true,
+	"indexed":        true,
+	"pipe":           true,
+	"escape":         true,
+}
+
+// valueOptions are the options of the form "name=value".
+var valueOptions = []string{"prefix", "suffix", "indexed", "del", "escape"}
+
+// exclusiveOptions are groups of options of which at most one may be given.
+var exclusiveOptions = [][]string{
+	{"comma", "space", "semicolon", "pipe", "del", "brackets", "numbered", "indexed"},
+	{"form", "spaceDelimited", "pipeDelimited", "deepObject"},
+	{"explode", "noexplode"},
+	{"unix", "unixmilli", "unixnano", "httpdate"},
+}
+
+// validate returns an error if the options are not valid for a field of type
+// t, as checked by Config.Strict.
+func (o tagOptions) validate(t reflect.Type) error {
+	for _, s := range o {
+		if i := strings.Index(s, "="); i >= 0 && o.isValueOption(s[:i]) {
+			continue
+		}
+		if !knownOptions[s] {
+			return fmt.Errorf("unknown option %q", s)
+		}
+	}
+
+	for _, group := range exclusiveOptions {
+		var found []string
+		for _, s := range group {
+			if _, ok := o.Get(s); ok || o.Contains(s) {
+				found = append(found, s)
+			}
+		}
+		if len(found) > 1 {
+			return fmt.Errorf("conflicting options %s", strings.Join(found, " and "))
+		}
+	}
+
+	// struct fields with these options are flattened unless they are
+	// recursive, and only reach here then
+	isStruct := indirectType(t).Kind() == reflect.Struct
+	if o.Contains("inline") && !isStruct {
+		return fmt.Errorf("option \"inline\" on non-struct type %v", t)
+	}
+	for _, name := range []string{"prefix", "suffix"} {
+		if _, ok := o.Get(name); ok && !isStruct {
+			return fmt.Errorf("option %q on non-struct type %v", name, t)
+		}
+	}
+	if s, ok := o.Get("indexed"); ok {
+		if n, err := strconv.Atoi(s); err != nil || n < 0 {
+			return fmt.Errorf("invalid index %q for option \"indexed\"", s)
+		}
+	}
+
+	if del, ok := o.Get("del"); ok && del == "" {
+		return fmt.Errorf("empty delimiter for option \"del\"")
+	}
+	if _, ok := escapePolicy(o); !ok {
+		s, _ := o.Get("escape")
+		return fmt.Errorf("unknown policy %q for option \"escape\"", s)
+	}
+	for _, s := range []string{"omitemptyelem", "keepempty", "escape"} {
+		if _, ok := o.Get(s); (ok || o.Contains(s)) && !isSequenceType(t) {
+			return fmt.Errorf("option %q on non-slice type %v", s, t)
+		}
+	}
+
+	// options apply to the elements of slices and arrays
+	base := t
+	for base.Kind() == reflect.Ptr || base.Kind() == reflect.Slice || base.Kind() == reflect.Array {
+		base = base.Elem()
+	}
+	if base.Kind() == reflect.Interface {
+		// the dynamic value is not known until encoding
+		return nil
+	}
+
+	if o.Contains("int") && base.Kind() != reflect.Bool {
+		return fmt.Errorf("option \"int\" on non-bool type %v", t)
+	}
+	for _, s := range exclusiveOptions[3] {
+		if o.Contains(s) && base != timeType {
+			return fmt.Errorf("option %q on non-time type %v", s, t)
+		}
+	}
+	return nil
+}
+
+// isSequenceType reports whether t is a slice or array type, or a pointer to
+// one, or an interface type that may hold one.
+func isSequenceType(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Interface:
+		return true
+	}
+	return false
+}
+
+// isValueOption reports whether name is one of the valueOptions.
+func (o tagOptions) isValueOption(name string) bool {
+	for _, s := range valueOptions {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns the value of the option of the form "name=value", and whether
+// it is present.
+func (o tagOptions) Get(name string) (string, bool) {
+	for _, s := range o {
+		if strings.HasPrefix(s, name+"=") {
+			return s[len(name)+1:], true
+		}
+	}
+	return "", false
+}
+
 // Contains checks whether the tagOptions contains the specified option.
 func (o tagOptions) Contains(option string) bool {
 	for _, s := range o {
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"net/url"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -84,6 +85,12 @@ func TestValues_BasicTypes(t *testing.T) {
 			}{time.Date(2000, 1, 1, 12, 34, 56, 0, time.UTC)},
 			url.Values{"V": {"946730096000000000"}},
 		},
+		{
+			struct {
+				V time.Time `url:",httpdate"`
+			}{time.Date(2000, 1, 1, 12, 34, 56, 0, time.FixedZone("", -3600))},
+			url.Values{"V": {"Sat, 01 Jan 2000 13:34:56 GMT"}},
+		},
 		{
 			struct {
 				V time.Time `layout:"2006-01-02"`
@@ -197,6 +204,36 @@ func TestValues_Slices(t *testing.T) {
 			}{[]string{"a", "b"}},
 			url.Values{"V0": {"a"}, "V1": {"b"}},
 		},
+		{
+			struct {
+				V []string `url:",indexed"`
+			}{[]string{"a", "b"}},
+			url.Values{"V[0]": {"a"}, "V[1]": {"b"}},
+		},
+		{
+			struct {
+				V []string `url:",indexed=1"`
+			}{[]string{"a", "b"}},
+			url.Values{"V[1]": {"a"}, "V[2]": {"b"}},
+		},
+		{
+			// delimiters are ignored
+			struct {
+				V []string `url:"v,indexed" del:","`
+			}{[]string{"a", "b"}},
+			url.Values{"v[0]": {"a"}, "v[1]": {"b"}},
+		},
+		{
+			// nested scopes
+			struct {
+				User struct {
+					IDs []int `url:"ids,indexed"`
+				} `url:"user"`
+			}{struct {
+				IDs []int `url:"ids,indexed"`
+			}{[]int{5, 6}}},
+			url.Values{"user[ids][0]": {"5"}, "user[ids][1]": {"6"}},
+		},
 
 		// arrays of strings
 		{
@@ -227,60 +264,901 @@ func TestValues_Slices(t *testing.T) {
 		},
 		{
 			struct {
-				V [2]string `url:",brackets"`
-			}{[2]string{"a", "b"}},
-			url.Values{"V[]": {"a", "b"}},
+				V [2]string `url:",brackets"`
+			}{[2]string{"a", "b"}},
+			url.Values{"V[]": {"a", "b"}},
+		},
+		{
+			struct {
+				V [2]string `url:",numbered"`
+			}{[2]string{"a", "b"}},
+			url.Values{"V0": {"a"}, "V1": {"b"}},
+		},
+
+		// custom delimiters
+		{
+			struct {
+				V []string `del:","`
+			}{[]string{"a", "b"}},
+			url.Values{"V": {"a,b"}},
+		},
+		{
+			struct {
+				V []string `del:"|"`
+			}{[]string{"a", "b"}},
+			url.Values{"V": {"a|b"}},
+		},
+		{
+			struct {
+				V []string `del:"🥑"`
+			}{[]string{"a", "b"}},
+			url.Values{"V": {"a🥑b"}},
+		},
+
+		{
+			struct {
+				V []string `url:",pipe"`
+			}{[]string{"a", "b"}},
+			url.Values{"V": {"a|b"}},
+		},
+		{
+			struct {
+				V []string `url:",del=::"`
+				W []int    `url:",int,del=!" del:"-"`
+			}{[]string{"a", "b"}, []int{1, 2}},
+			url.Values{"V": {"a::b"}, "W": {"1!2"}},
+		},
+		{
+			// del options are ignored without a value
+			struct {
+				V []string `url:",del=" del:"-"`
+			}{[]string{"a", "b"}},
+			url.Values{"V": {"a-b"}},
+		},
+
+		// delimiters within elements escaped with escape
+		{
+			struct {
+				V []string `url:",pipe"`
+				W []string `url:",pipe,escape"`
+				X []string `url:",comma,escape"`
+				Y []string `url:",del=::,escape"`
+			}{
+				[]string{"a|b", "c"},
+				[]string{"a|b", `c\`},
+				[]string{"a,b", "c|d"},
+				[]string{"a::b", "c:d"},
+			},
+			url.Values{
+				"V": {"a|b|c"},
+				"W": {`a\|b|c\\`},
+				"X": {`a\,b,c|d`},
+				"Y": {`a\::b::c:d`},
+			},
+		},
+		{
+			struct {
+				V []string `url:",escape"`
+			}{[]string{`a\`, "b,c"}},
+			url.Values{"V": {`a\`, "b,c"}},
+		},
+		{
+			struct {
+				V []string `url:",comma,escape=backslash"`
+				W []string `url:",comma,escape=percent"`
+				X []string `url:",del=::,escape=percent"`
+				Y []string `url:",semicolon,escape=quote"`
+				Z []string `url:",space,escape=error"`
+			}{
+				[]string{"a,b", "c"},
+				[]string{"a,b", "c%2C"},
+				[]string{"a::b", "c:d"},
+				[]string{"a;b", `c"d`, "e"},
+				[]string{"a", "b"},
+			},
+			url.Values{
+				"V": {`a\,b,c`},
+				"W": {"a%2Cb,c%252C"},
+				"X": {"a%3A%3Ab::c:d"},
+				"Y": {`"a;b";"c""d";e`},
+				"Z": {"a b"},
+			},
+		},
+
+		// slice of bools with additional options
+		{
+			struct {
+				V []bool `url:",space,int"`
+			}{[]bool{true, false}},
+			url.Values{"V": {"1 0"}},
+		},
+
+		// empty elements dropped with omitemptyelem
+		{
+			struct {
+				V []string `url:",omitemptyelem"`
+			}{[]string{"", "a", "", "b", ""}},
+			url.Values{"V": {"a", "b"}},
+		},
+		{
+			struct {
+				V []string `url:",comma,omitemptyelem"`
+			}{[]string{"a", "", "b"}},
+			url.Values{"V": {"a,b"}},
+		},
+		{
+			struct {
+				V []string `url:",comma,omitemptyelem"`
+			}{[]string{"", ""}},
+			url.Values{},
+		},
+		{
+			struct {
+				V [3]int `url:",numbered,omitemptyelem"`
+			}{[3]int{0, 1, 2}},
+			url.Values{"V0": {"1"}, "V1": {"2"}},
+		},
+		{
+			struct {
+				V []*string `url:",brackets,omitemptyelem"`
+			}{[]*string{nil, new(string)}},
+			url.Values{"V[]": {""}},
+		},
+
+		// empty slices encoded with keepempty
+		{
+			struct {
+				V []string `url:",keepempty"`
+				W []string `url:",keepempty"`
+			}{[]string{}, nil},
+			url.Values{"V": {""}},
+		},
+		{
+			struct {
+				V []string `url:",brackets,keepempty"`
+				W [0]int   `url:",comma,keepempty"`
+				X []int    `url:",numbered,keepempty"`
+			}{[]string{}, [0]int{}, []int{}},
+			url.Values{"V[]": {""}, "W": {""}, "X": {""}},
+		},
+		{
+			struct {
+				V []string `url:",keepempty"`
+			}{[]string{"a"}},
+			url.Values{"V": {"a"}},
+		},
+		{
+			struct {
+				V []string `url:",comma,omitemptyelem,keepempty"`
+				W []string `url:",omitemptyelem,keepempty"`
+			}{[]string{"", ""}, []string{"a", ""}},
+			url.Values{"V": {""}, "W": {"a"}},
+		},
+		{
+			// omitempty skips both nil and empty slices
+			struct {
+				V []string `url:",omitempty,keepempty"`
+				W []string `url:",omitempty,keepempty"`
+			}{[]string{}, nil},
+			url.Values{},
+		},
+	}
+
+	for _, tt := range tests {
+		testValue(t, tt.input, tt.want)
+	}
+}
+
+// Test the serialization examples from the OpenAPI 3 specification, see
+// https://spec.openapis.org/oas/v3.1.0#style-examples.  Empty values are
+// omitted since empty slices are never encoded.
+func TestValues_OpenAPIStyles(t *testing.T) {
+	type RGB struct {
+		R int `url:"R"`
+		G int `url:"G"`
+		B int `url:"B"`
+	}
+
+	str := "blue"
+	arr := []string{"blue", "black", "brown"}
+	obj := RGB{100, 200, 150}
+	m := map[string]int{"R": 100, "G": 200, "B": 150}
+
+	tests := []struct {
+		opts      string
+		primitive url.Values
+		array     url.Values
+		object    url.Values
+		objectMap url.Values // map keys are sorted
+	}{
+		{
+			"form,noexplode",
+			url.Values{"color": {"blue"}},
+			url.Values{"color": {"blue,black,brown"}},
+			url.Values{"color": {"R,100,G,200,B,150"}},
+			url.Values{"color": {"B,150,G,200,R,100"}},
+		},
+		{
+			"form",
+			url.Values{"color": {"blue"}},
+			url.Values{"color": {"blue", "black", "brown"}},
+			url.Values{"R": {"100"}, "G": {"200"}, "B": {"150"}},
+			url.Values{"R": {"100"}, "G": {"200"}, "B": {"150"}},
+		},
+		{
+			"form,explode",
+			url.Values{"color": {"blue"}},
+			url.Values{"color": {"blue", "black", "brown"}},
+			url.Values{"R": {"100"}, "G": {"200"}, "B": {"150"}},
+			url.Values{"R": {"100"}, "G": {"200"}, "B": {"150"}},
+		},
+		{
+			"spaceDelimited",
+			url.Values{"color": {"blue"}},
+			url.Values{"color": {"blue black brown"}},
+			url.Values{"color": {"R 100 G 200 B 150"}},
+			url.Values{"color": {"B 150 G 200 R 100"}},
+		},
+		{
+			"spaceDelimited,explode",
+			url.Values{"color": {"blue"}},
+			url.Values{"color": {"blue", "black", "brown"}},
+			url.Values{"R": {"100"}, "G": {"200"}, "B": {"150"}},
+			url.Values{"R": {"100"}, "G": {"200"}, "B": {"150"}},
+		},
+		{
+			"pipeDelimited",
+			url.Values{"color": {"blue"}},
+			url.Values{"color": {"blue|black|brown"}},
+			url.Values{"color": {"R|100|G|200|B|150"}},
+			url.Values{"color": {"B|150|G|200|R|100"}},
+		},
+		{
+			"pipeDelimited,explode",
+			url.Values{"color": {"blue"}},
+			url.Values{"color": {"blue", "black", "brown"}},
+			url.Values{"R": {"100"}, "G": {"200"}, "B": {"150"}},
+			url.Values{"R": {"100"}, "G": {"200"}, "B": {"150"}},
+		},
+		{
+			"deepObject",
+			url.Values{"color": {"blue"}},
+			url.Values{"color": {"blue", "black", "brown"}},
+			url.Values{"color[R]": {"100"}, "color[G]": {"200"}, "color[B]": {"150"}},
+			url.Values{"color[R]": {"100"}, "color[G]": {"200"}, "color[B]": {"150"}},
+		},
+	}
+
+	for _, tt := range tests {
+		// build a struct type with a single field tagged with tt.opts
+		typ := reflect.StructOf([]reflect.StructField{{
+			Name: "Color",
+			Type: reflect.TypeOf((*interface{})(nil)).Elem(),
+			Tag:  reflect.StructTag(`url:"color,` + tt.opts + `"`),
+		}})
+		input := func(v interface{}) interface{} {
+			s := reflect.New(typ).Elem()
+			s.Field(0).Set(reflect.ValueOf(v))
+			return s.Interface()
+		}
+
+		testValue(t, input(str), tt.primitive)
+		testValue(t, input(arr), tt.array)
+		testValue(t, input(obj), tt.object)
+		testValue(t, input(m), tt.objectMap)
+	}
+}
+
+func TestValues_OpenAPIStylesNested(t *testing.T) {
+	type RGB struct {
+		R int `url:"R"`
+		G int `url:"G"`
+	}
+
+	tests := []struct {
+		input interface{}
+		want  url.Values
+	}{
+		{
+			// exploded form objects are flattened into the enclosing scope
+			struct {
+				Filter struct {
+					Color RGB `url:"color,form"`
+				} `url:"filter"`
+			}{},
+			url.Values{"filter[R]": {"0"}, "filter[G]": {"0"}},
+		},
+		{
+			struct {
+				Filter struct {
+					Color RGB `url:"color,form,noexplode"`
+				} `url:"filter"`
+			}{},
+			url.Values{"filter[color]": {"R,0,G,0"}},
+		},
+		{
+			// objects containing slices and nested objects
+			struct {
+				V interface{} `url:"v,pipeDelimited"`
+			}{map[string]interface{}{"a": []int{1, 2}, "b": RGB{1, 2}}},
+			url.Values{"v": {"a|1|a|2|b[R]|1|b[G]|2"}},
+		},
+		{
+			// explicit delimiter options take precedence
+			struct {
+				V []string `url:"v,pipeDelimited,comma"`
+			}{[]string{"a", "b"}},
+			url.Values{"v": {"a,b"}},
+		},
+		{
+			// custom encoders within non-exploded objects
+			struct {
+				V struct {
+					A customEncodedStrings `url:"a"`
+				} `url:"v,form,noexplode"`
+			}{struct {
+				A customEncodedStrings `url:"a"`
+			}{customEncodedStrings{"x", "y"}}},
+			url.Values{"v": {"a.0,x,a.1,y"}},
+		},
+	}
+
+	for _, tt := range tests {
+		testValue(t, tt.input, tt.want)
+	}
+}
+
+func TestValues_NestedTypes(t *testing.T) {
+	type SubNested struct {
+		Value string `url:"value"`
+	}
+
+	type Nested struct {
+		A   SubNested  `url:"a"`
+		B   *SubNested `url:"b"`
+		Ptr *SubNested `url:"ptr,omitempty"`
+	}
+
+	tests := []struct {
+		input interface{}
+		want  url.Values
+	}{
+		{
+			struct {
+				Nest Nested `url:"nest"`
+			}{
+				Nested{
+					A: SubNested{
+						Value: "v",
+					},
+				},
+			},
+			url.Values{
+				"nest[a][value]": {"v"},
+				"nest[b]":        {""},
+			},
+		},
+		{
+			struct {
+				Nest Nested `url:"nest"`
+			}{
+				Nested{
+					Ptr: &SubNested{
+						Value: "v",
+					},
+				},
+			},
+			url.Values{
+				"nest[a][value]":   {""},
+				"nest[b]":          {""},
+				"nest[ptr][value]": {"v"},
+			},
+		},
+		{
+			// omitempty structs are skipped if all their fields are empty
+			struct {
+				Nest  Nested    `url:"nest,omitempty"`
+				Empty SubNested `url:"empty,omitempty"`
+				Full  SubNested `url:"full,omitempty"`
+			}{
+				Full: SubNested{Value: "v"},
+			},
+			url.Values{
+				"full[value]": {"v"},
+			},
+		},
+		{
+			struct {
+				Nest Nested `url:"nest,omitempty"`
+			}{
+				Nested{
+					A: SubNested{
+						Value: "v",
+					},
+				},
+			},
+			url.Values{
+				"nest[a][value]": {"v"},
+				"nest[b]":        {""},
+			},
+		},
+		{
+			nil,
+			url.Values{},
+		},
+	}
+
+	for _, tt := range tests {
+		testValue(t, tt.input, tt.want)
+	}
+}
+
+func TestValues_Interfaces(t *testing.T) {
+	type Nested struct {
+		Value string `url:"value"`
+	}
+
+	// stringer is an interface type that does not embed Encoder
+	type stringer interface {
+		String() string
+	}
+
+	// encoder is an interface type that embeds Encoder
+	type encoder interface {
+		Encoder
+	}
+
+	str := "s"
+	var nilInterface interface{}
+
+	tests := []struct {
+		input interface{}
+		want  url.Values
+	}{
+		// nil interfaces
+		{struct{ V interface{} }{}, url.Values{"V": {""}}},
+		{struct{ V stringer }{}, url.Values{"V": {""}}},
+		{struct{ V encoder }{}, url.Values{"V": {""}}},
+		{struct{ V *interface{} }{&nilInterface}, url.Values{"V": {""}}},
+		{
+			struct {
+				V interface{} `url:",omitempty"`
+			}{},
+			url.Values{},
+		},
+
+		// basic dynamic values
+		{struct{ V interface{} }{"v"}, url.Values{"V": {"v"}}},
+		{struct{ V interface{} }{&str}, url.Values{"V": {"s"}}},
+		{
+			struct {
+				V interface{} `url:",int"`
+			}{true},
+			url.Values{"V": {"1"}},
+		},
+
+		// time values
+		{
+			struct {
+				V interface{} `url:",unix"`
+			}{time.Date(2000, 1, 1, 12, 34, 56, 0, time.UTC)},
+			url.Values{"V": {"946730096"}},
+		},
+		{
+			struct {
+				V stringer `layout:"2006-01-02"`
+			}{time.Date(2000, 1, 1, 12, 34, 56, 0, time.UTC)},
+			url.Values{"V": {"2000-01-01"}},
+		},
+
+		// slices
+		{
+			struct {
+				V interface{} `url:",comma"`
+			}{[]string{"a", "b"}},
+			url.Values{"V": {"a,b"}},
+		},
+		{
+			struct {
+				V interface{} `url:",brackets"`
+			}{[]int{1, 2}},
+			url.Values{"V[]": {"1", "2"}},
+		},
+		{
+			struct {
+				V []interface{} `url:",comma,unix"`
+			}{[]interface{}{"a", time.Date(2000, 1, 1, 12, 34, 56, 0, time.UTC), nil}},
+			url.Values{"V": {"a,946730096,"}},
+		},
+
+		// nested structs
+		{
+			struct {
+				V interface{} `url:"v"`
+			}{Nested{"a"}},
+			url.Values{"v[value]": {"a"}},
+		},
+		{
+			struct {
+				V interface{} `url:"v"`
+			}{&Nested{"a"}},
+			url.Values{"v[value]": {"a"}},
+		},
+
+		// custom encoders
+		{
+			struct {
+				V encoder `url:"v"`
+			}{customEncodedInt(1)},
+			url.Values{"v": {"_1"}},
+		},
+		{
+			struct {
+				V interface{} `url:"v"`
+			}{customEncodedStrings{"a"}},
+			url.Values{"v.0": {"a"}},
+		},
+	}
+
+	for _, tt := range tests {
+		testValue(t, tt.input, tt.want)
+	}
+}
+
+// textKey is a map key type that implements encoding.TextMarshaler.
+type textKey struct {
+	a, b string
+}
+
+func (k textKey) MarshalText() ([]byte, error) {
+	return []byte(k.a + "." + k.b), nil
+}
+
+func TestValues_Maps(t *testing.T) {
+	type Nested struct {
+		Value string `url:"value"`
+	}
+	type stringKey string
+
+	tests := []struct {
+		input interface{}
+		want  url.Values
+	}{
+		// top level maps
+		{map[string]string(nil), url.Values{}},
+		{map[string]string{}, url.Values{}},
+		{map[string]string{"a": "1", "b": "2"}, url.Values{"a": {"1"}, "b": {"2"}}},
+		{&map[string]int{"a": 1}, url.Values{"a": {"1"}}},
+		{map[stringKey]bool{"a": true}, url.Values{"a": {"true"}}},
+		{map[textKey]string{{"a", "b"}: "1"}, url.Values{"a.b": {"1"}}},
+		{
+			url.Values{"a": {"1", "2"}, "b": {}},
+			url.Values{"a": {"1", "2"}},
+		},
+		{
+			map[string]interface{}{
+				"q":      "foo",
+				"all":    true,
+				"tags":   []string{"a", "b"},
+				"time":   time.Date(2000, 1, 1, 12, 34, 56, 0, time.UTC),
+				"nested": Nested{"v"},
+				"nil":    nil,
+			},
+			url.Values{
+				"q":             {"foo"},
+				"all":           {"true"},
+				"tags":          {"a", "b"},
+				"time":          {"2000-01-01T12:34:56Z"},
+				"nested[value]": {"v"},
+				"nil":           {""},
+			},
+		},
+
+		// nested maps
+		{
+			map[string]interface{}{
+				"filter": map[string]interface{}{
+					"page": 2,
+					"sub":  map[string]string{"a": "b"},
+				},
+			},
+			url.Values{
+				"filter[page]":   {"2"},
+				"filter[sub][a]": {"b"},
+			},
+		},
+		{
+			// map fields were formerly encoded using fmt.Sprint, as
+			// "m=map[a:1 b:2]", and are now scoped like nested structs
+			struct {
+				M map[string]int `url:"m"`
+			}{map[string]int{"a": 1, "b": 2}},
+			url.Values{"m[a]": {"1"}, "m[b]": {"2"}},
+		},
+		{
+			// other keys of nested maps are formatted using fmt.Sprint
+			struct{ M map[int]string }{map[int]string{1: "a", 2: "b"}},
+			url.Values{"M[1]": {"a"}, "M[2]": {"b"}},
+		},
+		{
+			map[string]interface{}{"m": map[float64]bool{1.5: true}},
+			url.Values{"m[1.5]": {"true"}},
+		},
+		{
+			// nil and empty map fields, formerly encoded as "m=map[]", add
+			// no parameters
+			struct {
+				M map[string]int `url:"m"`
+			}{},
+			url.Values{},
+		},
+		{
+			struct {
+				M map[string]int `url:"m"`
+			}{map[string]int{}},
+			url.Values{},
+		},
+	}
+
+	for _, tt := range tests {
+		testValue(t, tt.input, tt.want)
+	}
+}
+
+func TestValues_OmitEmpty(t *testing.T) {
+	str := ""
+
+	tests := []struct {
+		input interface{}
+		want  url.Values
+	}{
+		{struct{ v string }{}, url.Values{}}, // non-exported field
+		{
+			struct {
+				V string `url:",omitempty"`
+			}{},
+			url.Values{},
+		},
+		{
+			struct {
+				V string `url:"-"`
+			}{},
+			url.Values{},
+		},
+		{
+			struct {
+				V string `url:"omitempty"` // actually named omitempty
+			}{},
+			url.Values{"omitempty": {""}},
+		},
+		{
+			// include value for a non-nil pointer to an empty value
+			struct {
+				V *string `url:",omitempty"`
+			}{&str},
+			url.Values{"V": {""}},
+		},
+	}
+
+	for _, tt := range tests {
+		testValue(t, tt.input, tt.want)
+	}
+}
+
+func TestValues_EmbeddedStructs(t *testing.T) {
+	type Inner struct {
+		V string
+	}
+	type Outer struct {
+		Inner
+	}
+	type OuterPtr struct {
+		*Inner
+	}
+	type Mixed struct {
+		Inner
+		V string
+	}
+	type unexported struct {
+		Inner
+		V string
+	}
+	type Exported struct {
+		unexported
+	}
+	type Tagged struct {
+		V string `url:"V"`
+	}
+	type Other struct {
+		V string
+	}
+	type Ambiguous struct {
+		Inner
+		Other
+	}
+	type TaggedWins struct {
+		Inner
+		Tagged
+	}
+	type Deeper struct {
+		Ambiguous
+		Tagged
+	}
+	type Renamed struct {
+		V string
+		W string `url:"V"`
+	}
+	type RenamedWins struct {
+		Renamed
+		Other
+	}
+	type unexportedInt int
+	type EmbeddedInt struct {
+		unexportedInt
+		V string
+	}
+
+	tests := []struct {
+		input interface{}
+		want  url.Values
+	}{
+		{
+			Outer{Inner{V: "a"}},
+			url.Values{"V": {"a"}},
+		},
+		{
+			OuterPtr{&Inner{V: "a"}},
+			url.Values{"V": {"a"}},
+		},
+		{
+			Mixed{Inner: Inner{V: "a"}, V: "b"},
+			url.Values{"V": {"b"}},
+		},
+		{
+			// values from unexported embed are still included
+			Exported{
+				unexported{
+					Inner: Inner{V: "bar"},
+					V:     "foo",
+				},
+			},
+			url.Values{"V": {"foo"}},
+		},
+		{
+			// nil embedded pointers are skipped
+			OuterPtr{},
+			url.Values{},
+		},
+		{
+			// conflicting fields at the same depth are dropped
+			Ambiguous{Inner{V: "a"}, Other{V: "b"}},
+			url.Values{},
+		},
+		{
+			// tagged fields win over untagged fields at the same depth
+			TaggedWins{Inner{V: "a"}, Tagged{V: "b"}},
+			url.Values{"V": {"b"}},
+		},
+		{
+			// the shallower field wins even if a deeper field is ambiguous
+			Deeper{Ambiguous{Inner{V: "a"}, Other{V: "b"}}, Tagged{V: "c"}},
+			url.Values{"V": {"c"}},
+		},
+		{
+			// fields of the same struct with the same name are all included
+			struct {
+				A string `url:"v"`
+				B string `url:"v"`
+			}{"a", "b"},
+			url.Values{"v": {"a", "b"}},
+		},
+		{
+			// tagged fields do not hide untagged fields of the same struct
+			Renamed{"a", "b"},
+			url.Values{"V": {"a", "b"}},
+		},
+		{
+			RenamedWins{Renamed{"a", "b"}, Other{"c"}},
+			url.Values{"V": {"a", "b"}},
+		},
+		{
+			// unexported embedded non-struct types are ignored
+			EmbeddedInt{1, "a"},
+			url.Values{"V": {"a"}},
+		},
+	}
+
+	for _, tt := range tests {
+		testValue(t, tt.input, tt.want)
+	}
+}
+
+func TestValues_InlineStructs(t *testing.T) {
+	type Paging struct {
+		Page  int `url:"page"`
+		Limit int `url:"limit,omitempty"`
+	}
+	type Sort struct {
+		Order string `url:"order"`
+		Page  int    `url:"page"`
+	}
+
+	tests := []struct {
+		input interface{}
+		want  url.Values
+	}{
+		{
+			struct {
+				Q      string `url:"q"`
+				Paging Paging `url:",inline"`
+			}{"a", Paging{2, 10}},
+			url.Values{"q": {"a"}, "page": {"2"}, "limit": {"10"}},
 		},
 		{
+			// pointers, which are skipped if nil
 			struct {
-				V [2]string `url:",numbered"`
-			}{[2]string{"a", "b"}},
-			url.Values{"V0": {"a"}, "V1": {"b"}},
+				P *Paging `url:",inline"`
+				O *struct {
+					Order string `url:"order"`
+				} `url:",inline"`
+			}{&Paging{Page: 2}, nil},
+			url.Values{"page": {"2"}},
 		},
-
-		// custom delimiters
 		{
+			// same dominance rules as embedded structs
 			struct {
-				V []string `del:","`
-			}{[]string{"a", "b"}},
-			url.Values{"V": {"a,b"}},
+				Paging Paging `url:",inline"`
+				Page   int    `url:"page"`
+			}{Paging{2, 10}, 3},
+			url.Values{"page": {"3"}, "limit": {"10"}},
 		},
 		{
 			struct {
-				V []string `del:"|"`
-			}{[]string{"a", "b"}},
-			url.Values{"V": {"a|b"}},
+				Paging Paging `url:",inline"`
+				Sort   Sort   `url:",inline"`
+			}{Paging{2, 10}, Sort{"asc", 3}},
+			url.Values{"order": {"asc"}, "limit": {"10"}},
 		},
 		{
+			// inline is ignored for non-struct fields
 			struct {
-				V []string `del:"🥑"`
-			}{[]string{"a", "b"}},
-			url.Values{"V": {"a🥑b"}},
+				V int `url:"v,inline"`
+			}{1},
+			url.Values{"v": {"1"}},
 		},
-
-		// slice of bools with additional options
 		{
+			// nested scope
 			struct {
-				V []bool `url:",space,int"`
-			}{[]bool{true, false}},
-			url.Values{"V": {"1 0"}},
+				User struct {
+					Name   string `url:"name"`
+					Paging Paging `url:",inline"`
+				} `url:"user"`
+			}{},
+			url.Values{"user[name]": {""}, "user[page]": {"0"}},
 		},
 	}
 
 	for _, tt := range tests {
 		testValue(t, tt.input, tt.want)
 	}
-}
 
-func TestValues_NestedTypes(t *testing.T) {
-	type SubNested struct {
-		Value string `url:"value"`
+	c := &Config{Strict: true}
+	if _, err := c.Values(tests[0].input); err != nil {
+		t.Errorf("Values with Strict returned error: %v", err)
+	}
+	if _, err := c.Values(tests[4].input); err == nil {
+		t.Errorf("Values with Strict did not return an error for inline non-struct field")
 	}
+}
 
-	type Nested struct {
-		A   SubNested  `url:"a"`
-		B   *SubNested `url:"b"`
-		Ptr *SubNested `url:"ptr,omitempty"`
+func TestValues_PrefixSuffix(t *testing.T) {
+	type Range struct {
+		Min int `url:"min"`
+		Max int `url:"max,omitempty"`
+	}
+	type Filter struct {
+		Status  string `url:"status"`
+		Created Range  `url:",prefix=created_"`
 	}
 
 	tests := []struct {
@@ -289,145 +1167,410 @@ func TestValues_NestedTypes(t *testing.T) {
 	}{
 		{
 			struct {
-				Nest Nested `url:"nest"`
-			}{
-				Nested{
-					A: SubNested{
-						Value: "v",
-					},
-				},
-			},
+				Q      string `url:"q"`
+				Filter Filter `url:",prefix=filter_"`
+			}{"a", Filter{"open", Range{1, 2}}},
 			url.Values{
-				"nest[a][value]": {"v"},
-				"nest[b]":        {""},
+				"q":                  {"a"},
+				"filter_status":      {"open"},
+				"filter_created_min": {"1"},
+				"filter_created_max": {"2"},
 			},
 		},
 		{
+			// suffixes compose outside in
 			struct {
-				Nest Nested `url:"nest"`
-			}{
-				Nested{
-					Ptr: &SubNested{
-						Value: "v",
-					},
-				},
-			},
+				R *struct {
+					Range `url:",suffix=_at"`
+				} `url:",prefix=p_,suffix=_x"`
+			}{&struct {
+				Range `url:",suffix=_at"`
+			}{Range{Min: 1}}},
+			url.Values{"p_min_at_x": {"1"}},
+		},
+		{
+			// the same struct type under different prefixes
+			struct {
+				Price Range `url:",prefix=price_"`
+				Size  Range `url:",prefix=size_"`
+				Range
+			}{Range{1, 2}, Range{3, 0}, Range{5, 0}},
 			url.Values{
-				"nest[a][value]":   {""},
-				"nest[b]":          {""},
-				"nest[ptr][value]": {"v"},
+				"price_min": {"1"},
+				"price_max": {"2"},
+				"size_min":  {"3"},
+				"min":       {"5"},
 			},
 		},
 		{
-			nil,
-			url.Values{},
+			// prefixed names hide deeper fields with the same name
+			struct {
+				Outer Range `url:",prefix=a_"`
+				Inner struct {
+					Range `url:",prefix=min_"`
+				} `url:",prefix=a_"`
+				Min int `url:"a_min_min"`
+			}{Range{1, 0}, struct {
+				Range `url:",prefix=min_"`
+			}{Range{2, 0}}, 3},
+			url.Values{"a_min": {"1"}, "a_min_min": {"3"}},
+		},
+		{
+			// nested scope
+			struct {
+				User struct {
+					Age Range `url:",prefix=age_"`
+				} `url:"user"`
+			}{},
+			url.Values{"user[age_min]": {"0"}},
+		},
+		{
+			// prefix is ignored for non-struct fields
+			struct {
+				V int `url:"v,prefix=x_"`
+			}{1},
+			url.Values{"v": {"1"}},
 		},
 	}
 
 	for _, tt := range tests {
 		testValue(t, tt.input, tt.want)
 	}
+
+	c := &Config{Strict: true}
+	if _, err := c.Values(tests[0].input); err != nil {
+		t.Errorf("Values with Strict returned error: %v", err)
+	}
+	if _, err := c.Values(tests[5].input); err == nil {
+		t.Errorf("Values with Strict did not return an error for prefix on non-struct field")
+	}
 }
 
-func TestValues_OmitEmpty(t *testing.T) {
-	str := ""
+func TestValues_PrefixSuffixRecursive(t *testing.T) {
+	type Node struct {
+		Name  string `url:"name"`
+		Child *Node  `url:",prefix=child_"`
+	}
+	type Tree struct {
+		Root Node `url:",prefix=root_"`
+	}
 
 	tests := []struct {
 		input interface{}
 		want  url.Values
 	}{
-		{struct{ v string }{}, url.Values{}}, // non-exported field
-		{
-			struct {
-				V string `url:",omitempty"`
-			}{},
-			url.Values{},
-		},
 		{
-			struct {
-				V string `url:"-"`
-			}{},
-			url.Values{},
+			// recursive fields are encoded as nested structs
+			Node{Name: "a"},
+			url.Values{"name": {"a"}, "Child": {""}},
 		},
 		{
-			struct {
-				V string `url:"omitempty"` // actually named omitempty
-			}{},
-			url.Values{"omitempty": {""}},
+			Node{Name: "a", Child: &Node{Name: "b"}},
+			url.Values{"name": {"a"}, "Child[name]": {"b"}, "Child[Child]": {""}},
 		},
 		{
-			// include value for a non-nil pointer to an empty value
-			struct {
-				V *string `url:",omitempty"`
-			}{&str},
-			url.Values{"V": {""}},
+			Tree{Node{Name: "a"}},
+			url.Values{"root_name": {"a"}, "root_Child": {""}},
 		},
 	}
 
 	for _, tt := range tests {
 		testValue(t, tt.input, tt.want)
 	}
+
+	c := &Config{Strict: true}
+	if _, err := c.Values(tests[1].input); err != nil {
+		t.Errorf("Values with Strict returned error: %v", err)
+	}
+	if _, err := Describe(reflect.TypeOf(Tree{})); err != nil {
+		t.Errorf("Describe returned error: %v", err)
+	}
+	if _, err := Header(Tree{}); err != nil {
+		t.Errorf("Header returned error: %v", err)
+	}
 }
 
-func TestValues_EmbeddedStructs(t *testing.T) {
+func TestConfig_DisallowConflicts(t *testing.T) {
 	type Inner struct {
 		V string
 	}
-	type Outer struct {
-		Inner
+	type Other struct {
+		V string
 	}
-	type OuterPtr struct {
-		*Inner
+
+	c := &Config{DisallowConflicts: true}
+	tests := []struct {
+		input   interface{}
+		wantErr bool
+	}{
+		{struct{ Inner }{}, false},
+		{struct {
+			A string `url:"v"`
+			B string `url:"v"`
+		}{}, false},
+		{struct {
+			V string
+			W string `url:"V"`
+		}{}, false},
+		{struct {
+			Inner
+			V string
+		}{}, true},
+		{struct {
+			Inner
+			Other
+		}{}, true},
+		{struct {
+			S struct {
+				Inner
+				Other
+			}
+		}{}, true},
 	}
-	type Mixed struct {
-		Inner
-		V string
+
+	for _, tt := range tests {
+		_, err := c.Values(tt.input)
+		if got := err != nil; got != tt.wantErr {
+			t.Errorf("Values(%#v) returned error %v, want error: %t", tt.input, err, tt.wantErr)
+		}
 	}
-	type unexported struct {
-		Inner
-		V string
+}
+
+func TestConfig_Strict(t *testing.T) {
+	type Inner struct {
+		V string `url:"v,coma"`
 	}
-	type Exported struct {
-		unexported
+	type Filter struct {
+		Status string `url:"status"`
 	}
 
+	c := &Config{Strict: true}
 	tests := []struct {
-		input interface{}
-		want  url.Values
+		input   interface{}
+		wantErr string
+	}{
+		{struct {
+			A string      `url:"a,omitempty"`
+			B []bool      `url:"b,int,comma"`
+			C *time.Time  `url:"c,unixmilli"`
+			D []time.Time `url:"d,httpdate,brackets"`
+			E interface{} `url:"e,int,unix"`
+			F Inner       `url:"-"`
+			G []string    `url:"g,form,explode"`
+			H *[2]int     `url:"h,omitemptyelem"`
+			I []string    `url:"i,keepempty"`
+			J []string    `url:"j,indexed=1"`
+			K []string    `url:"k,del=::,escape"`
+			L []string    `url:"l,comma,escape=quote"`
+		}{}, ""},
+		{struct {
+			A string `url:"a,omitmepty"`
+		}{}, `unknown option "omitmepty"`},
+		{struct {
+			A string `url:"a,"`
+		}{}, `unknown option ""`},
+		{struct {
+			A []string `url:"a,comma,brackets"`
+		}{}, "conflicting options comma and brackets"},
+		{struct {
+			A []string `url:"a,form,explode,noexplode"`
+		}{}, "conflicting options explode and noexplode"},
+		{struct {
+			A int `url:"a,int"`
+		}{}, `option "int" on non-bool type int`},
+		{struct {
+			A int64 `url:"a,unix"`
+		}{}, `option "unix" on non-time type int64`},
+		{struct {
+			A string `url:"a,omitemptyelem"`
+		}{}, `option "omitemptyelem" on non-slice type string`},
+		{struct {
+			A []string `url:"a,indexed=x"`
+		}{}, `invalid index "x"`},
+		{struct {
+			A []string `url:"a,del="`
+		}{}, "empty delimiter"},
+		{struct {
+			A []string `url:"a,pipe,del=!"`
+		}{}, "conflicting options pipe and del"},
+		{struct {
+			A int `url:"a,escape"`
+		}{}, `option "escape" on non-slice type int`},
+		{struct {
+			A int `url:"a,escape=quote"`
+		}{}, `option "escape" on non-slice type int`},
+		{struct {
+			A []string `url:"a,comma,escape=html"`
+		}{}, `unknown policy "html"`},
+		{struct {
+			A []string `url:"a,brackets,indexed=1"`
+		}{}, "conflicting options brackets and indexed"},
+		{struct {
+			A map[string]string `url:"a,keepempty"`
+		}{}, `option "keepempty" on non-slice type map[string]string`},
+		{struct{ Inner }{}, "coma"},
+		{struct{ S Inner }{}, "coma"},
+		{struct {
+			F Filter `url:",prefix=filter_,omitempty"`
+		}{}, `option "omitempty" on flattened struct field`},
+		{struct {
+			F *Filter `url:",inline,omitempty"`
+		}{}, `option "omitempty" on flattened struct field`},
+		{struct {
+			F Filter `url:",inline,coma"`
+		}{}, `unknown option "coma"`},
+	}
+
+	for _, tt := range tests {
+		_, err := c.Values(tt.input)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("Values(%#v) returned error: %v", tt.input, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Values(%#v) returned error %v, want %q", tt.input, err, tt.wantErr)
+		}
+
+		// invalid tags are ignored when not strict
+		if _, err := Values(tt.input); err != nil {
+			t.Errorf("Values(%#v) without Strict returned error: %v", tt.input, err)
+		}
+	}
+
+	if _, err := c.Header(struct {
+		A string `header:"A,int"`
+	}{}); err == nil {
+		t.Errorf("Header with invalid tag did not return an error")
+	}
+	if _, err := c.Describe(reflect.TypeOf(struct{ Inner }{})); err == nil {
+		t.Errorf("Describe with invalid tag did not return an error")
+	}
+}
+
+func TestValues_InvalidInput(t *testing.T) {
+	tests := []interface{}{
+		"",
+		[]string{"a"},
+		map[int]string{1: "a"},
+		struct {
+			V []string `url:",comma,escape=error"`
+		}{[]string{"a,b"}},
+	}
+	for _, input := range tests {
+		_, err := Values(input)
+		if err == nil {
+			t.Errorf("expected Values(%#v) to return an error on invalid input", input)
+		}
+	}
+}
+
+func TestAppendValues(t *testing.T) {
+	type Options struct {
+		Page int      `url:"page"`
+		Tags []string `url:"tag"`
+	}
+
+	tests := []struct {
+		replace bool
+		dst     url.Values
+		input   interface{}
+		want    url.Values
 	}{
 		{
-			Outer{Inner{V: "a"}},
-			url.Values{"V": {"a"}},
+			false,
+			url.Values{},
+			Options{1, []string{"a"}},
+			url.Values{"page": {"1"}, "tag": {"a"}},
 		},
 		{
-			OuterPtr{&Inner{V: "a"}},
-			url.Values{"V": {"a"}},
+			false,
+			url.Values{"key": {"k"}, "page": {"0"}, "tag": {"a"}},
+			Options{1, []string{"b", "c"}},
+			url.Values{"key": {"k"}, "page": {"0", "1"}, "tag": {"a", "b", "c"}},
 		},
 		{
-			Mixed{Inner: Inner{V: "a"}, V: "b"},
-			url.Values{"V": {"b", "a"}},
+			true,
+			url.Values{"key": {"k"}, "page": {"0"}, "tag": {"a"}},
+			Options{1, []string{"b", "c"}},
+			url.Values{"key": {"k"}, "page": {"1"}, "tag": {"b", "c"}},
 		},
 		{
-			// values from unexported embed are still included
-			Exported{
-				unexported{
-					Inner: Inner{V: "bar"},
-					V:     "foo",
-				},
-			},
-			url.Values{"V": {"foo", "bar"}},
+			// keys not encoded by the input are left alone
+			true,
+			url.Values{"key": {"k"}, "tag": {"a"}},
+			Options{Page: 1},
+			url.Values{"key": {"k"}, "page": {"1"}, "tag": {"a"}},
 		},
 	}
 
 	for _, tt := range tests {
-		testValue(t, tt.input, tt.want)
+		c := &Config{Replace: tt.replace}
+		if err := c.AppendValues(tt.dst, tt.input); err != nil {
+			t.Errorf("AppendValues(%v, %#v) returned error: %v", tt.dst, tt.input, err)
+		}
+		if diff := cmp.Diff(tt.want, tt.dst); diff != "" {
+			t.Errorf("AppendValues(%#v) mismatch:\n%s", tt.input, diff)
+		}
+	}
+
+	if err := AppendValues(nil, Options{}); err == nil {
+		t.Errorf("AppendValues(nil) did not return an error")
+	}
+	if err := AppendValues(url.Values{}, ""); err == nil {
+		t.Errorf("AppendValues with invalid input did not return an error")
 	}
 }
 
-func TestValues_InvalidInput(t *testing.T) {
-	_, err := Values("")
-	if err == nil {
-		t.Errorf("expected Values() to return an error on invalid input")
+func TestAddToURL(t *testing.T) {
+	type Options struct {
+		Page   int    `url:"page"`
+		Cursor string `url:"cursor,omitempty"`
+	}
+
+	tests := []struct {
+		replace bool
+		url     string
+		input   interface{}
+		want    string
+	}{
+		{
+			false,
+			"https://example.com/path",
+			Options{Page: 2},
+			"https://example.com/path?page=2",
+		},
+		{
+			false,
+			"https://example.com/path?key=k&page=1#frag",
+			Options{Page: 2, Cursor: "c"},
+			"https://example.com/path?cursor=c&key=k&page=1&page=2#frag",
+		},
+		{
+			true,
+			"https://example.com/path?key=k&page=1",
+			Options{Page: 2},
+			"https://example.com/path?key=k&page=2",
+		},
+	}
+
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatalf("url.Parse(%q) returned error: %v", tt.url, err)
+		}
+		c := &Config{Replace: tt.replace}
+		if err := c.AddToURL(u, tt.input); err != nil {
+			t.Errorf("AddToURL(%q, %#v) returned error: %v", tt.url, tt.input, err)
+		}
+		if got := u.String(); got != tt.want {
+			t.Errorf("AddToURL(%q, %#v) = %q, want %q", tt.url, tt.input, got, tt.want)
+		}
+	}
+
+	if err := AddToURL(nil, Options{}); err == nil {
+		t.Errorf("AddToURL(nil) did not return an error")
 	}
 }
 
@@ -661,7 +1804,152 @@ func TestValues_CustomEncodingPointer(t *testing.T) {
 	}
 }
 
+// customEncodedStruct is a struct that encodes its fields itself, marked as
+// if its EncodeValues method were generated by querygen.
+type customEncodedStruct struct {
+	A string
+	B int `url:"b"`
+}
+
+func (m customEncodedStruct) EncodeValues(key string, v *url.Values) error {
+	if m.A == "err" {
+		return errors.New("encoding error")
+	}
+	v.Add(scopedName(key, "a"), "_"+m.A)
+	return nil
+}
+
+func (customEncodedStruct) QuerygenEncoder() {}
+
+// colorStruct is a struct whose EncodeValues method expects to be passed the
+// key of the field holding it.
+type colorStruct struct {
+	R, G, B uint8
+}
+
+func (c colorStruct) EncodeValues(key string, v *url.Values) error {
+	v.Set(key, fmt.Sprintf("%02x%02x%02x", c.R, c.G, c.B))
+	return nil
+}
+
+func TestValues_CustomEncodingStruct(t *testing.T) {
+	tests := []struct {
+		input interface{}
+		want  url.Values
+	}{
+		{customEncodedStruct{"x", 1}, url.Values{"a": {"_x"}}},
+		{&customEncodedStruct{"x", 1}, url.Values{"a": {"_x"}}},
+		{(*customEncodedStruct)(nil), url.Values{}},
+		{
+			struct {
+				S customEncodedStruct `url:"s"`
+			}{customEncodedStruct{"x", 1}},
+			url.Values{"s[a]": {"_x"}},
+		},
+
+		// top-level structs only use generated EncodeValues methods
+		{colorStruct{255, 0, 0}, url.Values{"R": {"255"}, "G": {"0"}, "B": {"0"}}},
+		{
+			struct {
+				C colorStruct `url:"c"`
+			}{colorStruct{255, 0, 0}},
+			url.Values{"c": {"ff0000"}},
+		},
+
+		// generated encoders do not support OpenAPI styles
+		{
+			struct {
+				S customEncodedStruct `url:"s,form,noexplode"`
+				T customEncodedStruct `url:"t,form"`
+				C colorStruct         `url:"c,form,noexplode"`
+			}{customEncodedStruct{"x", 1}, customEncodedStruct{"y", 2}, colorStruct{255, 0, 0}},
+			url.Values{"s": {"A,x,b,1"}, "A": {"y"}, "b": {"2"}, "c": {"ff0000"}},
+		},
+
+		// generated encoders are empty if all of their fields are
+		{
+			struct {
+				S customEncodedStruct `url:"s,omitempty"`
+				C colorStruct         `url:"c,omitempty"`
+			}{},
+			url.Values{"c": {"000000"}},
+		},
+	}
+
+	for _, tt := range tests {
+		testValue(t, tt.input, tt.want)
+	}
+
+	if _, err := Values(customEncodedStruct{A: "err"}); err == nil {
+		t.Errorf("Values did not return expected encoding error")
+	}
+
+	// the tags of generated encoders are checked using reflection
+	got, err := (&Config{Strict: true}).Values(customEncodedStruct{"x", 1})
+	if err != nil {
+		t.Errorf("Values with Strict returned error: %v", err)
+	}
+	if want := (url.Values{"A": {"x"}, "b": {"1"}}); !cmp.Equal(got, want) {
+		t.Errorf("Values with Strict = %v, want %v", got, want)
+	}
+}
+
+func TestEncodeField(t *testing.T) {
+	type Nested struct {
+		A string `url:"a"`
+	}
+	input := struct {
+		Tags  []bool
+		Time  time.Time
+		N     Nested
+		Skip  string
+		Color map[string]int
+		I     interface{}
+	}{
+		Tags:  []bool{true, false},
+		Time:  time.Date(2000, 1, 1, 12, 34, 56, 0, time.UTC),
+		N:     Nested{"x"},
+		Color: map[string]int{"R": 1, "G": 2},
+	}
+
+	tests := []struct {
+		scope, name string
+		field       interface{}
+		tag         reflect.StructTag
+		want        url.Values
+	}{
+		{"", "tags", &input.Tags, `url:"tags,int" del:"!"`, url.Values{"tags": {"1!0"}}},
+		{"s", "t", &input.Time, `layout:"2006-01-02"`, url.Values{"s[t]": {"2000-01-01"}}},
+		{"s", "n", &input.N, ``, url.Values{"s[n][a]": {"x"}}},
+		{"", "skip", &input.Skip, `url:"skip,omitempty"`, url.Values{}},
+		{"s", "color", &input.Color, `url:"color,form"`, url.Values{"s[G]": {"2"}, "s[R]": {"1"}}},
+		{"", "i", &input.I, `url:"i,omitempty"`, url.Values{}},
+		{"", "i", &input.I, ``, url.Values{"i": {""}}},
+	}
+
+	for _, tt := range tests {
+		var got url.Values
+		if err := EncodeField(&got, tt.scope, tt.name, tt.field, tt.tag); err != nil {
+			t.Errorf("EncodeField(%q, %q, %q) returned error: %v", tt.scope, tt.name, tt.tag, err)
+		}
+		if got == nil {
+			got = url.Values{}
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("EncodeField(%q, %q, %q) mismatch:\n%s", tt.scope, tt.name, tt.tag, diff)
+		}
+	}
+
+	v := make(url.Values)
+	if err := EncodeField(&v, "", "tags", input.Tags, ""); err == nil {
+		t.Errorf("EncodeField with non-pointer field did not return an error")
+	}
+}
+
 func TestIsEmptyValue(t *testing.T) {
+	type Inner struct {
+		A string
+	}
 	str := "string"
 	tests := []struct {
 		value interface{}
@@ -715,6 +2003,26 @@ func TestIsEmptyValue(t *testing.T) {
 		{struct{ int }{}, false},
 		{struct{ int }{0}, false},
 		{struct{ int }{1}, false},
+
+		// structs with encoded fields, which are empty if all of those are
+		{struct{ A, B int }{}, true},
+		{struct{ A, B int }{0, 1}, false},
+		{struct{ A []int }{[]int{}}, true},
+		{struct {
+			A int `url:"-"`
+			B string
+		}{1, ""}, true},
+		{struct{ A struct{ B string } }{}, true},
+		{struct{ A struct{ B string } }{struct{ B string }{"b"}}, false},
+		{struct{ A *struct{ B string } }{&struct{ B string }{}}, false},
+		{struct{ *Inner }{}, true},
+		{struct{ *Inner }{&Inner{}}, true},
+		{struct{ *Inner }{&Inner{"a"}}, false},
+
+		// structs implementing Encoder, unless it was generated
+		{colorStruct{}, false},
+		{customEncodedStruct{}, true},
+		{customEncodedStruct{"x", 0}, false},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,332 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package query
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// field represents a single struct field that is encoded as a URL parameter.
+type field struct {
+	name  string // URL parameter name, without any scope
+	tag   bool   // whether name was given in the struct tag
+	index []int  // index sequence for reaching the field from its struct
+	opts  tagOptions
+	sf    reflect.StructField
+}
+
+// structFields holds the encodable fields of a struct type after embedded
+// struct conflicts have been resolved.
+type structFields struct {
+	list []field
+
+	// conflicts lists the parameter names for which one or more fields were
+	// dropped because another field had the same name.
+	conflicts []string
+
+	// tagErr describes the first field in list with invalid tag options,
+	// which is reported when Config.Strict is set.
+	tagErr error
+}
+
+// placementTags are the struct tag keys that place a field somewhere other
+// than the query string.  Fields with any of these tags are not encoded as
+// URL parameters.
+var placementTags = []string{"path", "header"}
+
+type fieldCacheKey struct {
+	t   reflect.Type
+	key string
+}
+
+var fieldCache sync.Map // map[fieldCacheKey]*structFields
+
+// cachedTypeFields is like typeFields but uses a cache to avoid repeated work.
+func cachedTypeFields(t reflect.Type, key string) *structFields {
+	k := fieldCacheKey{t, key}
+	if f, ok := fieldCache.Load(k); ok {
+		return f.(*structFields)
+	}
+	f, _ := fieldCache.LoadOrStore(k, typeFields(t, key))
+	return f.(*structFields)
+}
+
+// typeFields returns the fields that should be encoded for the struct type t,
+// named by the struct tag with the given key.  For the "url" key, untagged
+// fields are named by their field name and fields with a placement tag are
+// skipped.  For other keys, only fields named in the tag are included.
+//
+// Embedded structs, and struct fields with the "inline", "prefix" or "suffix"
+// options, are followed breadth-first, with the names of their fields
+// adorned by any prefix and suffix.  Fields with the same name are resolved
+// using the same rules as the encoding/json package: a shallower field hides
+// deeper ones, and at the same depth a field named by its tag hides the
+// untagged ones of other structs.  Any remaining tie between fields of
+// different structs is ambiguous and all of those fields are dropped.  Fields
+// of a single struct that share a name are all kept.
+//
+// A struct is never followed from within itself, as a recursive type would
+// otherwise be followed forever when a prefix or suffix makes each level's
+// names differ.  Such a field is instead encoded as a nested struct.
+func typeFields(t reflect.Type, key string) *structFields {
+	type queued struct {
+		typ            reflect.Type
+		index          []int
+		prefix, suffix string         // added to the names of the struct's fields
+		path           []reflect.Type // structs followed to reach typ
+	}
+	type visit struct {
+		typ            reflect.Type
+		prefix, suffix string
+	}
+
+	var fields []field
+	var tagErr error // of a flattened struct field
+	var current []queued
+	next := []queued{{typ: t}}
+
+	// types already expanded at a shallower depth with the same names
+	visited := map[visit]bool{}
+
+	for len(next) > 0 {
+		current, next = next, nil
+
+		for _, q := range current {
+			if visited[visit{q.typ, q.prefix, q.suffix}] {
+				continue
+			}
+
+			for i := 0; i < q.typ.NumField(); i++ {
+				sf := q.typ.Field(i)
+				if sf.Anonymous {
+					t := sf.Type
+					if t.Kind() == reflect.Ptr {
+						t = t.Elem()
+					}
+					if sf.PkgPath != "" && t.Kind() != reflect.Struct {
+						// ignore embedded fields of unexported non-struct types
+						continue
+					}
+				} else if sf.PkgPath != "" { // unexported
+					continue
+				}
+
+				tag := sf.Tag.Get(key)
+				if tag == "-" || key == "url" && hasPlacementTag(sf) {
+					continue
+				}
+				name, opts := parseTag(tag)
+
+				index := make([]int, len(q.index)+1)
+				copy(index, q.index)
+				index[len(q.index)] = i
+
+				prefix, hasPrefix := opts.Get("prefix")
+				suffix, hasSuffix := opts.Get("suffix")
+				if name == "" && sf.Anonymous || opts.Contains("inline") || hasPrefix || hasSuffix {
+					ft := sf.Type
+					if ft.Kind() == reflect.Ptr {
+						ft = ft.Elem()
+					}
+					switch {
+					case ft.Kind() != reflect.Struct:
+					case ft == q.typ || containsType(q.path, ft):
+						if sf.Anonymous && name == "" {
+							// recursive embedded struct, already followed
+							continue
+						}
+					default:
+						// the struct field itself is not encoded, so its
+						// options are only checked
+						if tagErr == nil {
+							tagErr = validateFlattened(opts, sf)
+							if tagErr != nil {
+								tagErr = fmt.Errorf("query: invalid %s tag on field %s of %v: %v", key, sf.Name, t, tagErr)
+							}
+						}
+
+						// save embedded or inlined struct for processing at
+						// the next depth
+						path := append(q.path[:len(q.path):len(q.path)], q.typ)
+						next = append(next, queued{ft, index, q.prefix + prefix, suffix + q.suffix, path})
+						continue
+					}
+				}
+
+				f := field{
+					name:  name,
+					tag:   name != "",
+					index: index,
+					opts:  opts,
+					sf:    sf,
+				}
+				if f.name == "" {
+					if key != "url" {
+						continue
+					}
+					f.name = sf.Name
+				}
+				f.name = q.prefix + f.name + q.suffix
+				fields = append(fields, f)
+			}
+		}
+
+		for _, q := range current {
+			visited[visit{q.typ, q.prefix, q.suffix}] = true
+		}
+	}
+
+	sort.Slice(fields, func(i, j int) bool {
+		x := fields
+		if x[i].name != x[j].name {
+			return x[i].name < x[j].name
+		}
+		if len(x[i].index) != len(x[j].index) {
+			return len(x[i].index) < len(x[j].index)
+		}
+		if x[i].tag != x[j].tag {
+			return x[i].tag
+		}
+		return indexLess(x[i].index, x[j].index)
+	})
+
+	sfs := &structFields{}
+	for i, n := 0, 0; i < len(fields); i += n {
+		for n = 1; i+n < len(fields); n++ {
+			if fields[i+n].name != fields[i].name {
+				break
+			}
+		}
+		dominant := dominantFields(fields[i : i+n])
+		sfs.list = append(sfs.list, dominant...)
+		if len(dominant) < n {
+			sfs.conflicts = append(sfs.conflicts, fields[i].name)
+		}
+	}
+
+	sort.Slice(sfs.list, func(i, j int) bool {
+		return indexLess(sfs.list[i].index, sfs.list[j].index)
+	})
+
+	for _, f := range sfs.list {
+		if err := f.opts.validate(f.sf.Type); err != nil {
+			sfs.tagErr = fmt.Errorf("query: invalid %s tag on field %s of %v: %v", key, f.sf.Name, t, err)
+			break
+		}
+	}
+	if sfs.tagErr == nil {
+		sfs.tagErr = tagErr
+	}
+
+	return sfs
+}
+
+// validateFlattened checks the options opts of the struct field sf, whose
+// struct is flattened into the enclosing one.  The "omitempty" option is an
+// error, as the flattened fields are not omitted together.
+func validateFlattened(opts tagOptions, sf reflect.StructField) error {
+	if err := opts.validate(sf.Type); err != nil {
+		return err
+	}
+	if opts.Contains("omitempty") {
+		return fmt.Errorf("option \"omitempty\" on flattened struct field")
+	}
+	return nil
+}
+
+// hasPlacementTag reports whether sf has one of the placementTags, with a
+// value other than "-".
+func hasPlacementTag(sf reflect.StructField) bool {
+	for _, key := range placementTags {
+		if v, ok := sf.Tag.Lookup(key); ok && v != "-" {
+			return true
+		}
+	}
+	return false
+}
+
+// dominantFields returns the fields that should be encoded out of a set of
+// fields sharing the same name, sorted as in typeFields.  The result is empty
+// if the name is ambiguous.
+func dominantFields(fields []field) []field {
+	depth, tag := len(fields[0].index), fields[0].tag
+
+	n := 1
+	for n < len(fields) && len(fields[n].index) == depth && fields[n].tag == tag {
+		n++
+	}
+
+	// ties are only allowed between fields of the same struct
+	parent := fields[0].index[:depth-1]
+	for _, f := range fields[1:n] {
+		if !indexEqual(f.index[:depth-1], parent) {
+			return nil
+		}
+	}
+
+	// tagged fields only hide the untagged fields of other structs
+	dominant := fields[:n:n]
+	for _, f := range fields[n:] {
+		if len(f.index) == depth && indexEqual(f.index[:depth-1], parent) {
+			dominant = append(dominant, f)
+		}
+	}
+	return dominant
+}
+
+// containsType reports whether list contains t.
+func containsType(list []reflect.Type, t reflect.Type) bool {
+	for _, x := range list {
+		if x == t {
+			return true
+		}
+	}
+	return false
+}
+
+// indexLess reports whether index sequence a sorts before b.
+func indexLess(a, b []int) bool {
+	for k, x := range a {
+		if k >= len(b) {
+			return false
+		}
+		if x != b[k] {
+			return x < b[k]
+		}
+	}
+	return len(a) < len(b)
+}
+
+// indexEqual reports whether index sequences a and b are the same.
+func indexEqual(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k := range a {
+		if a[k] != b[k] {
+			return false
+		}
+	}
+	return true
+}
+
+// fieldByIndex returns the field of v reached by following index, where v is
+// a struct.  It reports false if a nil embedded struct pointer is encountered
+// along the way.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
@@ -0,0 +1,123 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package query
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+)
+
+// FormContentType is the content type of the request bodies returned by
+// FormBody.
+const FormContentType = "application/x-www-form-urlencoded"
+
+// FormBody returns an application/x-www-form-urlencoded request body for v,
+// along with its content type.
+//
+// FormBody accepts the same input and uses the same encoding rules as Values,
+// but rather than building the entire body in memory, parameters are encoded
+// and written to the returned reader as it is read.  Parameters appear in the
+// order they are encoded, rather than sorted by key as url.Values.Encode does.
+// An error while encoding is returned from Read.
+//
+// The caller must read the body to EOF or close it, otherwise the goroutine
+// encoding the body is never released.
+func FormBody(v interface{}) (body io.ReadCloser, contentType string, err error) {
+	return new(Config).FormBody(v)
+}
+
+// FormBody returns an application/x-www-form-urlencoded request body for v, as
+// described by the package level FormBody function, using the options in c.
+func (c *Config) FormBody(v interface{}) (body io.ReadCloser, contentType string, err error) {
+	val := reflect.ValueOf(v)
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			val = reflect.Value{}
+			break
+		}
+		val = val.Elem()
+	}
+
+	var encode func(valueAdder, reflect.Value, string) error
+	switch val.Kind() {
+	case reflect.Invalid:
+		encode = func(valueAdder, reflect.Value, string) error { return nil }
+	case reflect.Struct:
+		encode = c.reflectValue
+	case reflect.Map:
+		encode = c.reflectMapInput
+	default:
+		return nil, "", fmt.Errorf("query: FormBody() expects struct or map input. Got %v", val.Kind())
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		fw := &formWriter{w: bufio.NewWriter(pw)}
+		err := encode(fw, val, "")
+		if err == nil {
+			err = fw.err
+		}
+		if err == nil {
+			err = fw.w.Flush()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	return pr, FormContentType, nil
+}
+
+// NewFormRequest returns an *http.Request with the given method and URL, whose
+// body is the form encoding of v as returned by FormBody.
+//
+// The length of the body is not known in advance, so the request is sent using
+// chunked transfer encoding, and the body cannot be replayed on redirects.
+func NewFormRequest(method, url string, v interface{}) (*http.Request, error) {
+	return new(Config).NewFormRequest(method, url, v)
+}
+
+// NewFormRequest returns an *http.Request whose body is the form encoding of
+// v, as described by the package level NewFormRequest function, using the
+// options in c.
+func (c *Config) NewFormRequest(method, url string, v interface{}) (*http.Request, error) {
+	body, contentType, err := c.FormBody(v)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return req, nil
+}
+
+// formWriter is a valueAdder that writes each value to w in
+// application/x-www-form-urlencoded format.  The first error encountered
+// while writing is recorded in err, and later values are discarded.
+type formWriter struct {
+	w   *bufio.Writer
+	n   int
+	err error
+}
+
+// Add writes the key and value to f.w.
+func (f *formWriter) Add(key, value string) {
+	if f.err != nil {
+		return
+	}
+	if f.n > 0 {
+		f.w.WriteByte('&')
+	}
+	f.w.WriteString(url.QueryEscape(key))
+	f.w.WriteByte('=')
+	_, f.err = f.w.WriteString(url.QueryEscape(value))
+	f.n++
+}
@@ -0,0 +1,139 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package query
+
+import (
+	"io/ioutil"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestFormBody(t *testing.T) {
+	tests := []struct {
+		input interface{}
+		want  string
+	}{
+		{nil, ""},
+		{(*struct{})(nil), ""},
+		{struct{}{}, ""},
+		{
+			struct {
+				Query   string   `url:"q"`
+				ShowAll bool     `url:"all"`
+				Tags    []string `url:"tag"`
+			}{"foo bar", true, []string{"a&b", "c"}},
+			"q=foo+bar&all=true&tag=a%26b&tag=c",
+		},
+		{map[string]int{"b": 2, "a": 1}, "a=1&b=2"},
+	}
+
+	for _, tt := range tests {
+		body, contentType, err := FormBody(tt.input)
+		if err != nil {
+			t.Errorf("FormBody(%#v) returned error: %v", tt.input, err)
+			continue
+		}
+		if want := "application/x-www-form-urlencoded"; contentType != want {
+			t.Errorf("FormBody(%#v) returned content type %q, want %q", tt.input, contentType, want)
+		}
+		got, err := ioutil.ReadAll(body)
+		if err != nil {
+			t.Errorf("reading FormBody(%#v) returned error: %v", tt.input, err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("FormBody(%#v) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestFormBody_LargeSlice(t *testing.T) {
+	input := struct {
+		IDs []int `url:"id"`
+	}{}
+	for i := 0; i < 100000; i++ {
+		input.IDs = append(input.IDs, i)
+	}
+
+	body, _, err := FormBody(input)
+	if err != nil {
+		t.Fatalf("FormBody returned error: %v", err)
+	}
+	got, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fatalf("reading FormBody returned error: %v", err)
+	}
+
+	v, err := url.ParseQuery(string(got))
+	if err != nil {
+		t.Fatalf("url.ParseQuery returned error: %v", err)
+	}
+	ids := v["id"]
+	if len(ids) != len(input.IDs) {
+		t.Fatalf("FormBody encoded %d ids, want %d", len(ids), len(input.IDs))
+	}
+	for i, id := range ids {
+		if id != strconv.Itoa(i) {
+			t.Fatalf("FormBody encoded id %q at index %d", id, i)
+		}
+	}
+}
+
+func TestFormBody_Errors(t *testing.T) {
+	if _, _, err := FormBody("s"); err == nil {
+		t.Errorf("FormBody with invalid input did not return an error")
+	}
+
+	// encoding errors are returned when reading the body
+	body, _, err := FormBody(struct {
+		V customEncodedStrings
+	}{customEncodedStrings{"err"}})
+	if err != nil {
+		t.Fatalf("FormBody returned error: %v", err)
+	}
+	if _, err := ioutil.ReadAll(body); err == nil {
+		t.Errorf("reading FormBody did not return encoding error")
+	}
+
+	// closing the body early stops encoding
+	body, _, _ = FormBody(struct{ V string }{strings.Repeat("v", 1<<20)})
+	if err := body.Close(); err != nil {
+		t.Errorf("closing FormBody returned error: %v", err)
+	}
+}
+
+func TestNewFormRequest(t *testing.T) {
+	input := struct {
+		Query string `url:"q"`
+	}{"foo"}
+
+	req, err := NewFormRequest("POST", "https://example.com/search", input)
+	if err != nil {
+		t.Fatalf("NewFormRequest returned error: %v", err)
+	}
+	if req.Method != "POST" {
+		t.Errorf("NewFormRequest method = %q, want POST", req.Method)
+	}
+	if got, want := req.URL.String(), "https://example.com/search"; got != want {
+		t.Errorf("NewFormRequest URL = %q, want %q", got, want)
+	}
+	if got, want := req.Header.Get("Content-Type"), "application/x-www-form-urlencoded"; got != want {
+		t.Errorf("NewFormRequest Content-Type = %q, want %q", got, want)
+	}
+	if err := req.ParseForm(); err != nil {
+		t.Fatalf("ParseForm returned error: %v", err)
+	}
+	if got, want := req.PostForm.Get("q"), "foo"; got != want {
+		t.Errorf("NewFormRequest form value q = %q, want %q", got, want)
+	}
+
+	if _, err := NewFormRequest("POST", "://bad", input); err == nil {
+		t.Errorf("NewFormRequest with invalid URL did not return an error")
+	}
+	if _, err := NewFormRequest("POST", "https://example.com", ""); err == nil {
+		t.Errorf("NewFormRequest with invalid input did not return an error")
+	}
+}
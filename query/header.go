@@ -0,0 +1,97 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package query
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+// Header returns the http.Header encoding of v.
+//
+// Header expects to be passed a struct, and encodes each field that has a
+// "header" struct tag as an HTTP header named by the tag.  Fields without a
+// header tag are ignored, and fields with a header tag other than "-" are not
+// encoded as URL parameters by Values, so the same struct can describe both
+// the query string and the headers of a request.  For example:
+//
+//	type Options struct {
+//		Query       string    `url:"q"`
+//		IfNoneMatch string    `header:"If-None-Match,omitempty"`
+//		Since       time.Time `header:"If-Modified-Since,httpdate"`
+//		Languages   []string  `header:"Accept-Language,comma"`
+//	}
+//
+// Header names are canonicalized as by http.Header.Add.  The header tag
+// accepts the same options as the url tag, and values are formatted using the
+// same rules as Values: the "omitempty" option skips empty fields, the "int"
+// option encodes bools as "1" or "0", time.Time values accept the "unix",
+// "unixmilli", "unixnano" and "httpdate" options and the "layout" tag, and
+// slices and arrays are encoded as multiple values for the same header unless
+// a delimiter option such as "comma" is given.  Nested structs and maps are
+// not supported.
+func Header(v interface{}) (http.Header, error) {
+	return new(Config).Header(v)
+}
+
+// Header returns the http.Header encoding of v, as described by the package
+// level Header function, using the options in c.
+func (c *Config) Header(v interface{}) (http.Header, error) {
+	h := make(http.Header)
+
+	val := reflect.ValueOf(v)
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return h, nil
+		}
+		val = val.Elem()
+	}
+
+	switch val.Kind() {
+	case reflect.Invalid:
+		return h, nil
+	case reflect.Struct:
+	default:
+		return nil, fmt.Errorf("query: Header() expects struct input. Got %v", val.Kind())
+	}
+
+	fields := cachedTypeFields(val.Type(), "header")
+	if c.DisallowConflicts && len(fields.conflicts) > 0 {
+		return nil, fmt.Errorf("query: conflicting fields for header %q in %v", fields.conflicts[0], val.Type())
+	}
+	if c.Strict && fields.tagErr != nil {
+		return nil, fields.tagErr
+	}
+
+	for _, f := range fields.list {
+		sv, ok := fieldByIndex(val, f.index)
+		if !ok {
+			continue
+		}
+		if f.opts.Contains("omitempty") && isEmptyValue(sv) {
+			continue
+		}
+
+		if !sv.Type().Implements(encoderType) {
+			iv := sv
+			for iv.Kind() == reflect.Ptr || iv.Kind() == reflect.Interface {
+				if iv.IsNil() {
+					break
+				}
+				iv = iv.Elem()
+			}
+			if iv.Kind() == reflect.Map || iv.Kind() == reflect.Struct && iv.Type() != timeType {
+				return nil, fmt.Errorf("query: unsupported value for header %q: %v", f.name, iv.Type())
+			}
+		}
+
+		if err := c.reflectField(h, sv, "", f.name, f.opts, f.sf); err != nil {
+			return nil, err
+		}
+	}
+
+	return h, nil
+}
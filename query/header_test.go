@@ -0,0 +1,105 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package query
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestHeader(t *testing.T) {
+	type Common struct {
+		RequestID string `header:"X-Request-Id,omitempty"`
+	}
+
+	date := time.Date(2000, 1, 1, 12, 34, 56, 0, time.FixedZone("", 3600))
+
+	tests := []struct {
+		input interface{}
+		want  http.Header
+	}{
+		{nil, http.Header{}},
+		{(*struct{})(nil), http.Header{}},
+		{struct{ V string }{"v"}, http.Header{}},
+		{
+			struct {
+				Query       string    `url:"q"`
+				IfNoneMatch string    `header:"if-none-match"`
+				Since       time.Time `header:"If-Modified-Since,httpdate"`
+				Languages   []string  `header:"Accept-Language,comma"`
+			}{"q", `"etag"`, date, []string{"en", "fr"}},
+			http.Header{
+				"If-None-Match":     {`"etag"`},
+				"If-Modified-Since": {"Sat, 01 Jan 2000 11:34:56 GMT"},
+				"Accept-Language":   {"en,fr"},
+			},
+		},
+		{
+			struct {
+				Common
+				Skip  string    `header:"X-Skip,omitempty"`
+				Debug bool      `header:"X-Debug,int"`
+				Trace *int      `header:"X-Trace"`
+				Tags  []string  `header:"X-Tag"`
+				Unix  time.Time `header:"X-Time,unix"`
+			}{Common{"abc"}, "", true, nil, []string{"a", "b"}, date},
+			http.Header{
+				"X-Request-Id": {"abc"},
+				"X-Debug":      {"1"},
+				"X-Trace":      {""},
+				"X-Tag":        {"a", "b"},
+				"X-Time":       {"946726496"},
+			},
+		},
+		{
+			struct {
+				V customEncodedStrings `header:"X-V"`
+			}{customEncodedStrings{"a", "b"}},
+			http.Header{"X-V.0": {"a"}, "X-V.1": {"b"}},
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := Header(tt.input)
+		if err != nil {
+			t.Errorf("Header(%#v) returned error: %v", tt.input, err)
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("Header(%#v) mismatch:\n%s", tt.input, diff)
+		}
+	}
+}
+
+func TestHeader_Errors(t *testing.T) {
+	tests := []interface{}{
+		"s",
+		map[string]string{},
+		struct {
+			V struct{ A string } `header:"X-V"`
+		}{},
+		struct {
+			V map[string]string `header:"X-V"`
+		}{},
+	}
+
+	for _, input := range tests {
+		if _, err := Header(input); err == nil {
+			t.Errorf("Header(%#v) did not return an error", input)
+		}
+	}
+}
+
+func TestValues_HeaderFields(t *testing.T) {
+	input := struct {
+		Token string `header:"Authorization"`
+		Query string `url:"q"`
+		Page  int    `url:"page" header:"-"`
+	}{"secret", "foo", 2}
+	testValue(t, input, url.Values{"q": {"foo"}, "page": {"2"}})
+}
@@ -0,0 +1,195 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package query
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// A File is a file to be sent as a file part of a multipart/form-data body.
+type File struct {
+	// Name is the file name sent to the server.
+	Name string
+
+	// ContentType is the content type of the file part.  If empty,
+	// "application/octet-stream" is used.
+	ContentType string
+
+	// Reader supplies the contents of the file.
+	Reader io.Reader
+}
+
+var fileType = reflect.TypeOf(File{})
+
+var readerType = reflect.TypeOf(new(io.Reader)).Elem()
+
+// WriteMultipart writes the multipart/form-data encoding of v to w, without
+// closing w.
+//
+// WriteMultipart accepts the same input and uses the same encoding rules as
+// Values, with each URL parameter written as a form field in the order it is
+// encoded.  Fields holding a File, a *File, or any other non-nil io.Reader
+// (such as an *os.File) are instead written as file parts, including when they
+// are elements of a slice or array.  File parts are named in the same way as
+// other parameters, so the "brackets", "numbered" and "indexed" options and
+// nested scopes apply.  An io.Reader with a Name method, such as *os.File,
+// uses the base of that name as its file name; other readers use the
+// parameter name.
+func WriteMultipart(w *multipart.Writer, v interface{}) error {
+	return new(Config).WriteMultipart(w, v)
+}
+
+// WriteMultipart writes the multipart/form-data encoding of v to w, as
+// described by the package level WriteMultipart function, using the options
+// in c.
+func (c *Config) WriteMultipart(w *multipart.Writer, v interface{}) error {
+	val := reflect.ValueOf(v)
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return nil
+		}
+		val = val.Elem()
+	}
+
+	mw := &multipartWriter{w: w}
+	var err error
+	switch val.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Struct:
+		err = c.reflectValue(mw, val, "")
+	case reflect.Map:
+		err = c.reflectMapInput(mw, val, "")
+	default:
+		return fmt.Errorf("query: WriteMultipart() expects struct or map input. Got %v", val.Kind())
+	}
+	if err != nil {
+		return err
+	}
+	return mw.err
+}
+
+// MultipartBody returns a multipart/form-data request body for v, along with
+// its content type, which includes the multipart boundary.
+//
+// The body is encoded as by WriteMultipart while it is read, so that file
+// contents are not buffered in memory.  An error while encoding is returned
+// from Read.  The caller must read the body to EOF or close it, otherwise the
+// goroutine encoding the body is never released.
+func MultipartBody(v interface{}) (body io.ReadCloser, contentType string, err error) {
+	return new(Config).MultipartBody(v)
+}
+
+// MultipartBody returns a multipart/form-data request body for v, as described
+// by the package level MultipartBody function, using the options in c.
+func (c *Config) MultipartBody(v interface{}) (body io.ReadCloser, contentType string, err error) {
+	val := reflect.ValueOf(v)
+	for val.Kind() == reflect.Ptr && !val.IsNil() {
+		val = val.Elem()
+	}
+	switch val.Kind() {
+	case reflect.Invalid, reflect.Ptr, reflect.Struct, reflect.Map:
+	default:
+		return nil, "", fmt.Errorf("query: MultipartBody() expects struct or map input. Got %v", val.Kind())
+	}
+
+	pr, pw := io.Pipe()
+	w := multipart.NewWriter(pw)
+	go func() {
+		err := c.WriteMultipart(w, v)
+		if err == nil {
+			err = w.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	return pr, w.FormDataContentType(), nil
+}
+
+// fileAdder is implemented by valueAdders that can also accept files.
+type fileAdder interface {
+	addFile(key string, f File) error
+}
+
+// fileValue reports whether v holds a file, and if so returns it.  Readers
+// without a name of their own are named by key.
+func fileValue(v reflect.Value, key string) (File, bool) {
+	for v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return File{}, false
+		}
+		v = v.Elem()
+	}
+
+	switch {
+	case v.Type() == fileType:
+		return v.Interface().(File), true
+	case v.Kind() == reflect.Ptr && v.Type().Elem() == fileType:
+		if v.IsNil() {
+			return File{}, false
+		}
+		return v.Elem().Interface().(File), true
+	case v.Type().Implements(readerType):
+		if v.Kind() == reflect.Ptr && v.IsNil() || !v.CanInterface() {
+			return File{}, false
+		}
+		f := File{Name: key, Reader: v.Interface().(io.Reader)}
+		if n, ok := f.Reader.(interface{ Name() string }); ok {
+			f.Name = filepath.Base(n.Name())
+		}
+		return f, true
+	}
+	return File{}, false
+}
+
+// multipartWriter is a valueAdder that writes values as fields of a multipart
+// form, and files as file parts.  The first error encountered while writing
+// is recorded in err, and later values are discarded.
+type multipartWriter struct {
+	w   *multipart.Writer
+	err error
+}
+
+// Add writes the key and value as a form field.
+func (m *multipartWriter) Add(key, value string) {
+	if m.err != nil {
+		return
+	}
+	m.err = m.w.WriteField(key, value)
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// addFile writes f as a file part named key.
+func (m *multipartWriter) addFile(key string, f File) error {
+	if m.err != nil {
+		return m.err
+	}
+
+	contentType := f.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition",
+		fmt.Sprintf(`form-data; name="%s"; filename="%s"`, quoteEscaper.Replace(key), quoteEscaper.Replace(f.Name)))
+	h.Set("Content-Type", contentType)
+
+	part, err := m.w.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	if f.Reader != nil {
+		_, err = io.Copy(part, f.Reader)
+	}
+	return err
+}
@@ -0,0 +1,172 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package query
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// part is a simplified multipart part for comparison in tests.
+type part struct {
+	Name, FileName, ContentType, Content string
+}
+
+// readParts reads all of the parts of the multipart body r.
+func readParts(t *testing.T, r io.Reader, boundary string) []part {
+	t.Helper()
+	mr := multipart.NewReader(r, boundary)
+	var parts []part
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			return parts
+		}
+		if err != nil {
+			t.Fatalf("NextPart returned error: %v", err)
+		}
+		b, err := ioutil.ReadAll(p)
+		if err != nil {
+			t.Fatalf("reading part returned error: %v", err)
+		}
+		parts = append(parts, part{p.FormName(), p.FileName(), p.Header.Get("Content-Type"), string(b)})
+	}
+}
+
+func TestWriteMultipart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "query")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "report.txt")
+	if err := ioutil.WriteFile(name, []byte("report"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	osFile, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer osFile.Close()
+
+	type Meta struct {
+		Title string `url:"title"`
+	}
+
+	input := struct {
+		Meta    Meta      `url:"meta"`
+		Tags    []string  `url:"tag,brackets"`
+		Avatar  File      `url:"avatar"`
+		Report  *os.File  `url:"report"`
+		Data    io.Reader `url:"data"`
+		Docs    []File    `url:"doc,numbered"`
+		Missing *File     `url:"missing,omitempty"`
+	}{
+		Meta:   Meta{"hello"},
+		Tags:   []string{"a", "b"},
+		Avatar: File{"me.png", "image/png", strings.NewReader("png")},
+		Report: osFile,
+		Data:   bytes.NewBufferString("data"),
+		Docs: []File{
+			{Name: "a.txt", Reader: strings.NewReader("a")},
+			{Name: "b.txt", Reader: strings.NewReader("b")},
+		},
+	}
+
+	buf := new(bytes.Buffer)
+	w := multipart.NewWriter(buf)
+	if err := WriteMultipart(w, input); err != nil {
+		t.Fatalf("WriteMultipart returned error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []part{
+		{"meta[title]", "", "", "hello"},
+		{"tag[]", "", "", "a"},
+		{"tag[]", "", "", "b"},
+		{"avatar", "me.png", "image/png", "png"},
+		{"report", "report.txt", "application/octet-stream", "report"},
+		{"data", "data", "application/octet-stream", "data"},
+		{"doc0", "a.txt", "application/octet-stream", "a"},
+		{"doc1", "b.txt", "application/octet-stream", "b"},
+	}
+	if diff := cmp.Diff(want, readParts(t, buf, w.Boundary())); diff != "" {
+		t.Errorf("WriteMultipart mismatch:\n%s", diff)
+	}
+}
+
+func TestWriteMultipart_Errors(t *testing.T) {
+	w := multipart.NewWriter(ioutil.Discard)
+	if err := WriteMultipart(w, "s"); err == nil {
+		t.Errorf("WriteMultipart with invalid input did not return an error")
+	}
+	if err := WriteMultipart(w, struct{ V customEncodedStrings }{customEncodedStrings{"err"}}); err == nil {
+		t.Errorf("WriteMultipart did not return encoding error")
+	}
+	if err := WriteMultipart(w, struct{ F io.Reader }{errReader{}}); err == nil {
+		t.Errorf("WriteMultipart did not return reader error")
+	}
+	if _, _, err := MultipartBody(1); err == nil {
+		t.Errorf("MultipartBody with invalid input did not return an error")
+	}
+}
+
+// errReader is an io.Reader that always returns an error.
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, io.ErrUnexpectedEOF
+}
+
+func TestMultipartBody(t *testing.T) {
+	input := map[string]interface{}{
+		"q":    "foo",
+		"file": File{Name: "f.txt", ContentType: "text/plain", Reader: strings.NewReader("contents")},
+	}
+
+	body, contentType, err := MultipartBody(input)
+	if err != nil {
+		t.Fatalf("MultipartBody returned error: %v", err)
+	}
+
+	req, err := http.NewRequest("POST", "https://example.com", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	if err := req.ParseMultipartForm(1 << 20); err != nil {
+		t.Fatalf("ParseMultipartForm returned error: %v", err)
+	}
+
+	if got, want := req.MultipartForm.Value["q"], []string{"foo"}; !cmp.Equal(got, want) {
+		t.Errorf("MultipartBody value q = %q, want %q", got, want)
+	}
+	files := req.MultipartForm.File["file"]
+	if len(files) != 1 {
+		t.Fatalf("MultipartBody has %d files, want 1", len(files))
+	}
+	if got, want := files[0].Filename, "f.txt"; got != want {
+		t.Errorf("MultipartBody file name = %q, want %q", got, want)
+	}
+	f, err := files[0].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if b, _ := ioutil.ReadAll(f); string(b) != "contents" {
+		t.Errorf("MultipartBody file contents = %q, want %q", b, "contents")
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package query
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// An OpenAPIParameter is an OpenAPI 3 parameter object describing a query
+// parameter.  It marshals to JSON (or YAML using JSON tags) in the form used
+// by OpenAPI documents.
+type OpenAPIParameter struct {
+	Name    string         `json:"name"`
+	In      string         `json:"in"`
+	Style   string         `json:"style,omitempty"`
+	Explode *bool          `json:"explode,omitempty"`
+	Schema  *OpenAPISchema `json:"schema"`
+}
+
+// An OpenAPISchema is the subset of an OpenAPI 3 schema object needed to
+// describe the values of query parameters.  An empty schema allows any value.
+type OpenAPISchema struct {
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Items                *OpenAPISchema            `json:"items,omitempty"`
+	Properties           map[string]*OpenAPISchema `json:"properties,omitempty"`
+	AdditionalProperties *OpenAPISchema            `json:"additionalProperties,omitempty"`
+}
+
+// OpenAPIParameters returns OpenAPI 3 parameter objects describing the query
+// parameters that Values encodes from a struct of type t, in the order they
+// are encoded.  The parameters are those returned by Describe, with schemas
+// derived from their Go types, and styles derived from their options:
+//
+//	options                 style            explode
+//	(none)                  form             true
+//	brackets                form             true, named with "[]" suffix
+//	comma, form,noexplode   form             false
+//	space, spaceDelimited   spaceDelimited   false
+//	pipeDelimited           pipeDelimited    false
+//
+// Slices joined by other delimiters, or with elements escaped by the "escape"
+// option, are described as strings.  Maps are described as deepObject
+// parameters unless another style is given in their options.
+//
+// time.Time values are described as strings with the "date-time" format, or
+// the "date" format if their layout is "2006-01-02", or as integers if they
+// are encoded as Unix times.
+//
+// Slices with the "numbered" or "indexed" options cannot be described by
+// OpenAPI, and cause an error to be returned.
+func OpenAPIParameters(t reflect.Type) ([]OpenAPIParameter, error) {
+	return new(Config).OpenAPIParameters(t)
+}
+
+// OpenAPIParameters returns OpenAPI 3 parameter objects describing the query
+// parameters encoded from a struct of type t, as described by the package
+// level OpenAPIParameters function, using the options in c.
+func (c *Config) OpenAPIParameters(t reflect.Type) ([]OpenAPIParameter, error) {
+	params, err := c.Describe(t)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]OpenAPIParameter, 0, len(params))
+	for _, p := range params {
+		op, err := openAPIParameter(p)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, op)
+	}
+	return out, nil
+}
+
+// openAPIParameter returns the OpenAPI parameter object for p.
+func openAPIParameter(p Param) (OpenAPIParameter, error) {
+	op := OpenAPIParameter{Name: p.Key, In: "query"}
+	t := indirectType(p.Type)
+
+	explode := func(style string, explode bool) {
+		op.Style, op.Explode = style, &explode
+	}
+
+	switch {
+	case p.SliceStyle == "numbered" || p.SliceStyle == "indexed":
+		return op, fmt.Errorf("query: %s parameter %q cannot be described by OpenAPI", p.SliceStyle, p.Key)
+	case p.SliceStyle == "delimited":
+		// the "error" policy leaves elements unchanged
+		plain := p.Escape == "" || p.Escape == "error"
+		switch {
+		case p.Delimiter == "," && plain:
+			explode("form", false)
+		case p.Delimiter == " " && plain:
+			explode("spaceDelimited", false)
+		case p.Delimiter == "|" && plain:
+			explode("pipeDelimited", false)
+		default:
+			op.Schema = &OpenAPISchema{Type: "string"}
+			return op, nil
+		}
+		op.Schema = &OpenAPISchema{Type: "array", Items: openAPISchema(p, t.Elem())}
+	case p.SliceStyle != "":
+		if p.SliceStyle == "brackets" {
+			op.Name += "[]"
+		}
+		explode("form", true)
+		op.Schema = &OpenAPISchema{Type: "array", Items: openAPISchema(p, t.Elem())}
+	case p.Encoder || t.Kind() == reflect.Interface:
+		op.Schema = &OpenAPISchema{}
+	case t.Kind() == reflect.Map:
+		if p.Style != "" && p.Style != "deepObject" {
+			explode(p.Style, p.Explode)
+		} else {
+			explode("deepObject", true)
+		}
+		op.Schema = openAPISchema(p, t)
+	case t.Kind() == reflect.Struct && t != timeType:
+		// structs joined into a single value by their style
+		explode(p.Style, false)
+		op.Schema = openAPISchema(p, t)
+	default:
+		op.Schema = openAPISchema(p, t)
+	}
+	return op, nil
+}
+
+// openAPISchema returns the schema of values of type t, which are encoded
+// using the options of p.
+func openAPISchema(p Param, t reflect.Type) *OpenAPISchema {
+	t = indirectType(t)
+
+	if t == timeType {
+		switch p.TimeFormat {
+		case "unix", "unixmilli", "unixnano":
+			return &OpenAPISchema{Type: "integer", Format: "int64"}
+		case "rfc3339":
+			return &OpenAPISchema{Type: "string", Format: "date-time"}
+		case "layout":
+			if p.Layout == "2006-01-02" {
+				return &OpenAPISchema{Type: "string", Format: "date"}
+			}
+		}
+		return &OpenAPISchema{Type: "string"}
+	}
+
+	if t.Implements(stringerType) || t.Implements(errorType) {
+		return &OpenAPISchema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		if p.Int {
+			return &OpenAPISchema{Type: "integer"}
+		}
+		return &OpenAPISchema{Type: "boolean"}
+	case reflect.Int32, reflect.Uint16, reflect.Int16, reflect.Uint8, reflect.Int8:
+		return &OpenAPISchema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &OpenAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &OpenAPISchema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &OpenAPISchema{Type: "number", Format: "double"}
+	case reflect.Map:
+		return &OpenAPISchema{Type: "object", AdditionalProperties: openAPISchema(Param{}, t.Elem())}
+	case reflect.Struct:
+		s := &OpenAPISchema{Type: "object", Properties: map[string]*OpenAPISchema{}}
+		for _, f := range cachedTypeFields(t, "url").list {
+			ft := indirectType(f.sf.Type)
+			if ft.Kind() == reflect.Struct && ft != timeType {
+				// nested properties are not described, which also
+				// avoids recursing into recursive types
+				s.Properties[f.name] = &OpenAPISchema{Type: "object"}
+				continue
+			}
+			fp := Param{Int: f.opts.Contains("int")}
+			fp.TimeFormat, fp.Layout = timeFormat(f.opts, f.sf)
+			s.Properties[f.name] = openAPISchema(fp, f.sf.Type)
+		}
+		return s
+	case reflect.Interface:
+		return &OpenAPISchema{}
+	}
+	return &OpenAPISchema{Type: "string"}
+}
+
+var (
+	stringerType = reflect.TypeOf(new(fmt.Stringer)).Elem()
+	errorType    = reflect.TypeOf(new(error)).Elem()
+)
+
+// indirectType returns the type reached by following pointers from t.
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,114 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package query
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestOpenAPIParameters(t *testing.T) {
+	type Point struct {
+		X  int     `url:"x"`
+		Y  float64 `url:"y"`
+		On bool    `url:"on,int"`
+	}
+	type State string
+	type Options struct {
+		Query   string              `url:"q"`
+		Page    int32               `url:"page,omitempty"`
+		All     bool                `url:"all"`
+		Since   time.Time           `url:"since"`
+		Until   *time.Time          `url:"until,unixmilli"`
+		Day     time.Time           `url:"day" layout:"2006-01-02"`
+		Tags    []string            `url:"tag"`
+		IDs     []int64             `url:"id,comma"`
+		Words   []string            `url:"word,space"`
+		Colors  []string            `url:"color,pipeDelimited"`
+		Flags   []bool              `url:"flag,int,brackets"`
+		Bits    []uint8             `url:"bits,semicolon"`
+		Size    Point               `url:"size,form,noexplode"`
+		Point   Point               `url:"point"`
+		Filter  map[string]string   `url:"filter"`
+		Labels  map[string]int      `url:"labels,form"`
+		States  []State             `url:"state"`
+		Extra   interface{}         `url:"extra"`
+		Custom  customEncodedInt    `url:"custom"`
+		Ratio32 float32             `url:"ratio"`
+		Pipes   []string            `url:"pipe,pipe"`
+		Quoted  []string            `url:"quoted,comma,escape"`
+		Checked []string            `url:"checked,comma,escape=error"`
+		Gen     customEncodedStruct `url:"gen"`
+	}
+
+	got, err := OpenAPIParameters(reflect.TypeOf(Options{}))
+	if err != nil {
+		t.Fatalf("OpenAPIParameters returned error: %v", err)
+	}
+
+	want := `[
+	{"name":"q","in":"query","schema":{"type":"string"}},
+	{"name":"page","in":"query","schema":{"type":"integer","format":"int32"}},
+	{"name":"all","in":"query","schema":{"type":"boolean"}},
+	{"name":"since","in":"query","schema":{"type":"string","format":"date-time"}},
+	{"name":"until","in":"query","schema":{"type":"integer","format":"int64"}},
+	{"name":"day","in":"query","schema":{"type":"string","format":"date"}},
+	{"name":"tag","in":"query","style":"form","explode":true,"schema":{"type":"array","items":{"type":"string"}}},
+	{"name":"id","in":"query","style":"form","explode":false,"schema":{"type":"array","items":{"type":"integer","format":"int64"}}},
+	{"name":"word","in":"query","style":"spaceDelimited","explode":false,"schema":{"type":"array","items":{"type":"string"}}},
+	{"name":"color","in":"query","style":"pipeDelimited","explode":false,"schema":{"type":"array","items":{"type":"string"}}},
+	{"name":"flag[]","in":"query","style":"form","explode":true,"schema":{"type":"array","items":{"type":"integer"}}},
+	{"name":"bits","in":"query","schema":{"type":"string"}},
+	{"name":"size","in":"query","style":"form","explode":false,"schema":{"type":"object","properties":{
+		"on":{"type":"integer"},"x":{"type":"integer","format":"int64"},"y":{"type":"number","format":"double"}}}},
+	{"name":"point[x]","in":"query","schema":{"type":"integer","format":"int64"}},
+	{"name":"point[y]","in":"query","schema":{"type":"number","format":"double"}},
+	{"name":"point[on]","in":"query","schema":{"type":"integer"}},
+	{"name":"filter","in":"query","style":"deepObject","explode":true,"schema":{"type":"object","additionalProperties":{"type":"string"}}},
+	{"name":"labels","in":"query","style":"form","explode":true,"schema":{"type":"object","additionalProperties":{"type":"integer","format":"int64"}}},
+	{"name":"state","in":"query","style":"form","explode":true,"schema":{"type":"array","items":{"type":"string"}}},
+	{"name":"extra","in":"query","schema":{}},
+	{"name":"custom","in":"query","schema":{}},
+	{"name":"ratio","in":"query","schema":{"type":"number","format":"float"}},
+	{"name":"pipe","in":"query","style":"pipeDelimited","explode":false,"schema":{"type":"array","items":{"type":"string"}}},
+	{"name":"quoted","in":"query","schema":{"type":"string"}},
+	{"name":"checked","in":"query","style":"form","explode":false,"schema":{"type":"array","items":{"type":"string"}}},
+	{"name":"gen[A]","in":"query","schema":{"type":"string"}},
+	{"name":"gen[b]","in":"query","schema":{"type":"integer","format":"int64"}}
+]`
+	b, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wantBuf bytes.Buffer
+	if err := json.Compact(&wantBuf, []byte(want)); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(wantBuf.String(), string(b)); diff != "" {
+		t.Errorf("OpenAPIParameters mismatch:\n%s", diff)
+	}
+}
+
+func TestOpenAPIParameters_Errors(t *testing.T) {
+	tests := []reflect.Type{
+		reflect.TypeOf(0),
+		reflect.TypeOf(struct {
+			IDs []int `url:"id,numbered"`
+		}{}),
+		reflect.TypeOf(struct {
+			IDs []int `url:"id,indexed"`
+		}{}),
+	}
+	for _, typ := range tests {
+		if _, err := OpenAPIParameters(typ); err == nil {
+			t.Errorf("OpenAPIParameters(%v) did not return an error", typ)
+		}
+	}
+}
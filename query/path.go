@@ -0,0 +1,436 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package query
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// Path returns pattern with each "{name}" placeholder replaced by the value of
+// the field of v whose "path" struct tag has that name.  For example:
+//
+//	type Options struct {
+//		Owner string `path:"owner"`
+//		Repo  string `path:"repo"`
+//		State string `url:"state"`
+//	}
+//
+//	opt := Options{"google", "go-querystring", "open"}
+//	p, _ := query.Path("/repos/{owner}/{repo}/issues", opt)
+//	fmt.Print(p) // will output: "/repos/google/go-querystring/issues"
+//
+// Fields with a path tag other than "-" are not encoded as URL parameters by
+// Values.  The path tag accepts the same options as the url tag for
+// formatting values, and fields are resolved in embedded structs using the
+// same rules.  Each value is escaped as a single path segment, so "/" within
+// a value is encoded as "%2F".  Slices and arrays are encoded as a
+// comma-separated list unless another delimiter option is given.  Each
+// element is escaped separately, so the delimiter is only escaped if it may
+// not appear in a path segment.  Values implementing Encoder are formatted by
+// their EncodeValues methods, as described by Template.
+//
+// Path returns an error if pattern contains a placeholder with no matching
+// field, or if v has a path field that is not used by any placeholder, or if
+// a field's value is a nil pointer, struct, or map.
+func Path(pattern string, v interface{}) (string, error) {
+	return new(Config).Path(pattern, v)
+}
+
+// Path returns pattern with its placeholders replaced by the path fields of
+// v, as described by the package level Path function, using the options in c.
+func (c *Config) Path(pattern string, v interface{}) (string, error) {
+	val := reflect.ValueOf(v)
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			val = reflect.Value{}
+			break
+		}
+		val = val.Elem()
+	}
+
+	var fields []field
+	switch val.Kind() {
+	case reflect.Invalid:
+	case reflect.Struct:
+		sfs := cachedTypeFields(val.Type(), "path")
+		if c.DisallowConflicts && len(sfs.conflicts) > 0 {
+			return "", fmt.Errorf("query: conflicting fields for path parameter %q in %v", sfs.conflicts[0], val.Type())
+		}
+		if c.Strict && sfs.tagErr != nil {
+			return "", sfs.tagErr
+		}
+		fields = sfs.list
+	default:
+		return "", fmt.Errorf("query: Path() expects struct input. Got %v", val.Kind())
+	}
+
+	used := make(map[string]bool)
+	b := new(strings.Builder)
+	s := pattern
+	for s != "" {
+		i := strings.IndexByte(s, '{')
+		if i < 0 {
+			b.WriteString(s)
+			break
+		}
+		b.WriteString(s[:i])
+
+		n := strings.IndexByte(s[i:], '}')
+		if n < 0 {
+			return "", fmt.Errorf("query: unclosed placeholder in path %q", pattern)
+		}
+		name := s[i+1 : i+n]
+		s = s[i+n+1:]
+
+		f, ok := findField(fields, name)
+		if !ok {
+			return "", fmt.Errorf("query: no path field for placeholder %q", name)
+		}
+		used[name] = true
+
+		seg, err := c.pathSegment(val, f)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(seg)
+	}
+
+	for _, f := range fields {
+		if !used[f.name] {
+			return "", fmt.Errorf("query: path field %q is not used in path %q", f.name, pattern)
+		}
+	}
+
+	return b.String(), nil
+}
+
+// pathSegment returns the escaped value of the path field f of the struct v.
+func (c *Config) pathSegment(v reflect.Value, f field) (string, error) {
+	sv, ok := fieldByIndex(v, f.index)
+	if !ok {
+		return "", fmt.Errorf("query: no value for path parameter %q", f.name)
+	}
+
+	p, err := c.valueParts(sv, f.name, f.opts, f.sf)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case p.kind == reflect.String && p.del != "":
+		// elements are escaped separately, so that a delimiter that may
+		// appear in a path segment, such as a comma, is left as is
+		list := make([]string, len(p.list))
+		for i, e := range p.list {
+			list[i] = url.PathEscape(e)
+		}
+		return strings.Join(list, escapePath(p.del, "")), nil
+	case p.kind == reflect.String:
+		return url.PathEscape(p.str), nil
+	case p.kind == reflect.Slice:
+		list := make([]string, len(p.list))
+		for i, e := range p.list {
+			list[i] = url.PathEscape(e)
+		}
+		return strings.Join(list, ","), nil
+	case p.kind == reflect.Map:
+		return "", fmt.Errorf("query: unsupported value for path parameter %q: %v", f.name, sv.Type())
+	}
+	return "", fmt.Errorf("query: no value for path parameter %q", f.name)
+}
+
+// findField returns the first of fields with the given name.
+func findField(fields []field, name string) (field, bool) {
+	for _, f := range fields {
+		if f.name == name {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+// Matrix returns the OpenAPI "matrix" style path encoding of v, such as
+// ";id=5;tags=a,b".
+//
+// Matrix accepts the same input as Values, and each URL parameter produced by
+// Values becomes a single matrix parameter, in the order that the fields of v
+// are encoded.  URL tag options apply as usual, so slices are encoded as
+// repeated parameters unless an option such as "comma" is given, and nested
+// structs and maps are scoped as in Values.  A parameter with an empty value
+// is encoded as just its name, such as ";id".
+func Matrix(v interface{}) (string, error) {
+	return new(Config).Matrix(v)
+}
+
+// Matrix returns the OpenAPI "matrix" style path encoding of v, as described
+// by the package level Matrix function, using the options in c.
+func (c *Config) Matrix(v interface{}) (string, error) {
+	val := reflect.ValueOf(v)
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return "", nil
+		}
+		val = val.Elem()
+	}
+
+	var pairs orderedValues
+	var err error
+	switch val.Kind() {
+	case reflect.Invalid:
+		return "", nil
+	case reflect.Struct:
+		err = c.reflectValue(&pairs, val, "")
+	case reflect.Map:
+		err = c.reflectMapInput(&pairs, val, "")
+	default:
+		return "", fmt.Errorf("query: Matrix() expects struct or map input. Got %v", val.Kind())
+	}
+	if err != nil {
+		return "", err
+	}
+
+	s := new(strings.Builder)
+	for _, p := range pairs {
+		writeMatrixParam(s, escapePath(p.key, ";="), escapePath(p.value, ";"))
+	}
+	return s.String(), nil
+}
+
+// MatrixParam returns the OpenAPI "matrix" style path encoding of a single
+// parameter with the given name and value.  If explode is false, slices and
+// arrays are encoded as ";name=a,b" and structs and maps as
+// ";name=k1,v1,k2,v2".  If explode is true, they are encoded as
+// ";name=a;name=b" and ";k1=v1;k2=v2" respectively.  Other values are encoded
+// as ";name=value", or just ";name" if the value is empty.
+func MatrixParam(name string, v interface{}, explode bool) (string, error) {
+	return new(Config).MatrixParam(name, v, explode)
+}
+
+// MatrixParam returns the OpenAPI "matrix" style path encoding of a single
+// parameter, as described by the package level MatrixParam function, using
+// the options in c.
+func (c *Config) MatrixParam(name string, v interface{}, explode bool) (string, error) {
+	p, err := c.valueParts(reflect.ValueOf(v), name, nil, reflect.StructField{})
+	if err != nil {
+		return "", err
+	}
+
+	name = escapePath(name, ";,=")
+	s := new(strings.Builder)
+	switch {
+	case p.kind == reflect.Slice && explode:
+		for _, e := range p.list {
+			writeMatrixParam(s, name, escapePath(e, ";,="))
+		}
+	case p.kind == reflect.Slice:
+		writeMatrixParam(s, name, joinEscaped(p.list, ",", ";,="))
+	case p.kind == reflect.Map && explode:
+		for _, kv := range p.pairs {
+			writeMatrixParam(s, escapePath(kv.key, ";,="), escapePath(kv.value, ";,="))
+		}
+	case p.kind == reflect.Map:
+		writeMatrixParam(s, name, joinEscaped(p.flatten(), ",", ";,="))
+	default:
+		writeMatrixParam(s, name, escapePath(p.str, ";,="))
+	}
+	return s.String(), nil
+}
+
+// Label returns the OpenAPI "label" style path encoding of v, such as ".a.b".
+// Slices and arrays are encoded as ".a.b" regardless of explode.  If explode
+// is false, structs and maps are encoded as ".k1.v1.k2.v2", and if it is true
+// they are encoded as ".k1=v1.k2=v2".  Other values are encoded as ".value".
+func Label(v interface{}, explode bool) (string, error) {
+	return new(Config).Label(v, explode)
+}
+
+// Label returns the OpenAPI "label" style path encoding of v, as described by
+// the package level Label function, using the options in c.
+func (c *Config) Label(v interface{}, explode bool) (string, error) {
+	p, err := c.valueParts(reflect.ValueOf(v), "", nil, reflect.StructField{})
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case p.kind == reflect.Slice:
+		return "." + joinEscaped(p.list, ".", ",="), nil
+	case p.kind == reflect.Map && explode:
+		s := new(strings.Builder)
+		for _, kv := range p.pairs {
+			s.WriteByte('.')
+			s.WriteString(escapePath(kv.key, ",="))
+			s.WriteByte('=')
+			s.WriteString(escapePath(kv.value, ",="))
+		}
+		return s.String(), nil
+	case p.kind == reflect.Map:
+		return "." + joinEscaped(p.flatten(), ".", ",="), nil
+	}
+	return "." + escapePath(p.str, ",="), nil
+}
+
+// writeMatrixParam writes a single matrix parameter to s.
+func writeMatrixParam(s *strings.Builder, name, value string) {
+	s.WriteByte(';')
+	s.WriteString(name)
+	if value != "" {
+		s.WriteByte('=')
+		s.WriteString(value)
+	}
+}
+
+// valueParts is an individual value broken into the parts used by path style
+// encodings.
+type valueParts struct {
+	// kind is reflect.Slice for slices and arrays, reflect.Map for structs
+	// and maps, reflect.Invalid for nil values, and reflect.String for all
+	// other values.
+	kind reflect.Kind
+
+	list  []string   // slice or array elements
+	pairs []keyValue // struct fields or map entries, in encoding order
+	str   string     // any other value
+
+	// del is the delimiter joining the elements in list to form str, for
+	// slices and arrays with a delimiter option.
+	del string
+}
+
+// flatten returns the keys and values of p.pairs as a single list.
+func (p valueParts) flatten() []string {
+	list := make([]string, 0, 2*len(p.pairs))
+	for _, kv := range p.pairs {
+		list = append(list, kv.key, kv.value)
+	}
+	return list
+}
+
+// valueParts breaks v, the value of the parameter name, into its parts,
+// formatting each with opts.  The pairs of a struct or map are the URL
+// parameters encoded from it, unscoped.  Slices and arrays with a delimiter
+// option are joined into a single string.  Values implementing Encoder are
+// broken into the values their EncodeValues method adds for name.
+func (c *Config) valueParts(v reflect.Value, name string, opts tagOptions, sf reflect.StructField) (valueParts, error) {
+	for {
+		// generated encoders are broken into their fields, as they would be
+		// if they were not generated
+		if v.IsValid() && v.Type().Implements(encoderType) && !v.Type().Implements(generatedEncoderType) {
+			return encoderParts(v, name)
+		}
+		if v.Kind() != reflect.Ptr && v.Kind() != reflect.Interface {
+			break
+		}
+		if v.IsNil() {
+			return valueParts{kind: reflect.Invalid}, nil
+		}
+		v = v.Elem()
+	}
+
+	var p valueParts
+	switch {
+	case !v.IsValid():
+		p.kind = reflect.Invalid
+	case v.Kind() == reflect.Slice || v.Kind() == reflect.Array:
+		p.kind = reflect.Slice
+		for _, e := range sliceElems(v, opts) {
+			p.list = append(p.list, valueString(e, opts, sf))
+		}
+		if del := sliceDelimiter(opts, sf); del != "" {
+			// elements joined by a delimiter form a single value
+			p.kind = reflect.String
+			for i, s := range p.list {
+				elem, err := escapeDelimiter(s, del, opts)
+				if err != nil {
+					return p, err
+				}
+				p.list[i] = elem
+			}
+			p.str = strings.Join(p.list, del)
+			p.del = del
+		}
+	case v.Kind() == reflect.Struct && v.Type() != timeType:
+		p.kind = reflect.Map
+		var pairs orderedValues
+		if err := c.reflectValue(&pairs, v, ""); err != nil {
+			return p, err
+		}
+		p.pairs = pairs
+	case v.Kind() == reflect.Map:
+		p.kind = reflect.Map
+		var pairs orderedValues
+		if err := c.reflectMap(&pairs, v, ""); err != nil {
+			return p, err
+		}
+		p.pairs = pairs
+	default:
+		p.kind = reflect.String
+		p.str = valueString(v, opts, sf)
+	}
+	return p, nil
+}
+
+// encoderParts returns the parts of the value v of the parameter name, which
+// implements Encoder.  Several values added for name form a list, and any
+// parameter other than name is an error.
+func encoderParts(v reflect.Value, name string) (valueParts, error) {
+	// a nil pointer is encoded as the zero value of a non-pointer receiver,
+	// as in Values
+	if !reflect.Indirect(v).IsValid() && v.Type().Elem().Implements(encoderType) {
+		v = reflect.New(v.Type().Elem())
+	}
+
+	uv := make(url.Values)
+	if err := v.Interface().(Encoder).EncodeValues(name, &uv); err != nil {
+		return valueParts{}, err
+	}
+	for k := range uv {
+		if k != name {
+			return valueParts{}, fmt.Errorf("query: unsupported value for %q: %v encodes parameter %q", name, v.Type(), k)
+		}
+	}
+
+	switch vs := uv[name]; len(vs) {
+	case 0:
+		return valueParts{kind: reflect.Invalid}, nil
+	case 1:
+		return valueParts{kind: reflect.String, str: vs[0]}, nil
+	default:
+		return valueParts{kind: reflect.Slice, list: vs}, nil
+	}
+}
+
+// joinEscaped escapes each element of list using escapePath and joins them
+// with sep.
+func joinEscaped(list []string, sep, reserved string) string {
+	s := new(strings.Builder)
+	for i, e := range list {
+		if i > 0 {
+			s.WriteString(sep)
+		}
+		s.WriteString(escapePath(e, reserved))
+	}
+	return s.String()
+}
+
+// escapePath percent-encodes s for use within a single path segment.  All
+// bytes other than the RFC 3986 unreserved characters and the sub-delimiters,
+// ":" and "@" that are allowed in a path segment are escaped, as are any
+// characters in reserved.
+func escapePath(s, reserved string) string {
+	return percentEscape(s, func(s string, i int) bool {
+		c := s[i]
+		return isUnreserved(c) || strings.IndexByte("!$&'()*+,;=:@", c) >= 0 && strings.IndexByte(reserved, c) < 0
+	})
+}
+
+// isUnreserved reports whether c is an RFC 3986 unreserved character.
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
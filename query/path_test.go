@@ -0,0 +1,308 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package query
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestPath(t *testing.T) {
+	type Repo struct {
+		Owner string `path:"owner"`
+		Repo  string `path:"repo"`
+	}
+
+	id := 5
+	tests := []struct {
+		pattern string
+		input   interface{}
+		want    string
+	}{
+		{"/static", nil, "/static"},
+		{"/static", struct{ V string }{"v"}, "/static"},
+		{
+			"/repos/{owner}/{repo}/issues",
+			struct {
+				Owner string `path:"owner"`
+				Repo  string `path:"repo"`
+				State string `url:"state"`
+			}{"google", "go-querystring", "open"},
+			"/repos/google/go-querystring/issues",
+		},
+		{
+			// embedded structs and repeated placeholders
+			"/{owner}/{repo}/{owner}",
+			&struct{ Repo }{Repo{"a", "b"}},
+			"/a/b/a",
+		},
+		{
+			// values are escaped as path segments
+			"/files/{name}",
+			struct {
+				Name string `path:"name"`
+			}{"a/b c?d"},
+			"/files/a%2Fb%20c%3Fd",
+		},
+		{
+			"/items/{id}/{ids}/{tags}",
+			struct {
+				ID   *int     `path:"id"`
+				IDs  []int    `path:"ids"`
+				Tags []string `path:"tags,space"`
+			}{&id, []int{1, 2}, []string{"a", "b"}},
+			"/items/5/1,2/a%20b",
+		},
+		{
+			"/tags/{tags}",
+			struct {
+				Tags []string `path:"tags,omitemptyelem"`
+			}{[]string{"a", "", "b"}},
+			"/tags/a,b",
+		},
+		{
+			// elements are escaped before being joined
+			"/x/{ids}",
+			struct {
+				IDs []string `path:"ids,comma"`
+			}{[]string{"a", "b/c", "d e"}},
+			"/x/a,b%2Fc,d%20e",
+		},
+		{
+			"/x/{ids}",
+			struct {
+				IDs []string `path:"ids,comma,escape"`
+			}{[]string{"a,b", "c"}},
+			"/x/a%5C%2Cb,c",
+		},
+		{
+			"/tags/{tags}",
+			struct {
+				Tags []string `path:"tags,pipe,escape"`
+			}{[]string{"a|b", "c"}},
+			"/tags/a%5C%7Cb%7Cc",
+		},
+		{
+			// values implementing Encoder
+			"/a/{n}/{c}",
+			struct {
+				N codeValue   `path:"n"`
+				C colorStruct `path:"c"`
+			}{7, colorStruct{0, 128, 255}},
+			"/a/C7/0080ff",
+		},
+		{
+			"/since/{t}/{on}",
+			struct {
+				T  time.Time `path:"t,unix"`
+				On bool      `path:"on,int"`
+			}{time.Date(2000, 1, 1, 12, 34, 56, 0, time.UTC), true},
+			"/since/946730096/1",
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := Path(tt.pattern, tt.input)
+		if err != nil {
+			t.Errorf("Path(%q, %#v) returned error: %v", tt.pattern, tt.input, err)
+		}
+		if got != tt.want {
+			t.Errorf("Path(%q, %#v) = %q, want %q", tt.pattern, tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestPath_Errors(t *testing.T) {
+	type Repo struct {
+		Owner string `path:"owner"`
+	}
+
+	tests := []struct {
+		pattern string
+		input   interface{}
+	}{
+		{"/{owner}", nil},
+		{"/{owner}", struct{}{}},
+		{"/{owner}/{repo}", Repo{"a"}},
+		{"/static", Repo{"a"}},
+		{"/{owner", Repo{"a"}},
+		{"/{owner}", "s"},
+		{"/{owner}", struct {
+			Owner *string `path:"owner"`
+		}{}},
+		{"/{owner}", struct {
+			Owner struct{ A string } `path:"owner"`
+		}{}},
+	}
+
+	for _, tt := range tests {
+		if _, err := Path(tt.pattern, tt.input); err == nil {
+			t.Errorf("Path(%q, %#v) did not return an error", tt.pattern, tt.input)
+		}
+	}
+}
+
+func TestValues_PathFields(t *testing.T) {
+	input := struct {
+		Owner string `path:"owner"`
+		Other string `url:"other" path:"other"`
+		State string `url:"state"`
+		Color string `url:"c" path:"-"`
+	}{"google", "x", "open", "red"}
+	testValue(t, input, url.Values{"state": {"open"}, "c": {"red"}})
+
+	got, err := Expand("/repos/{owner}{?state}", input)
+	if err != nil {
+		t.Errorf("Expand returned error: %v", err)
+	}
+	if want := "/repos/google?state=open"; got != want {
+		t.Errorf("Expand = %q, want %q", got, want)
+	}
+}
+
+func TestMatrix(t *testing.T) {
+	type Nested struct {
+		A string `url:"a"`
+	}
+
+	tests := []struct {
+		input interface{}
+		want  string
+	}{
+		{nil, ""},
+		{(*struct{})(nil), ""},
+		{struct{}{}, ""},
+		{
+			struct {
+				ID   int      `url:"id"`
+				Tags []string `url:"tags,comma"`
+			}{5, []string{"a", "b"}},
+			";id=5;tags=a,b",
+		},
+		{
+			struct {
+				Tags []string `url:"tags"`
+			}{[]string{"a", "b"}},
+			";tags=a;tags=b",
+		},
+		{
+			struct {
+				Empty string `url:"empty"`
+				Skip  string `url:"skip,omitempty"`
+				Off   bool   `url:"off,int"`
+			}{},
+			";empty;off=0",
+		},
+		{
+			struct {
+				T time.Time `url:"t,unix"`
+				N Nested    `url:"n"`
+			}{time.Date(2000, 1, 1, 12, 34, 56, 0, time.UTC), Nested{"x"}},
+			";t=946730096;n%5Ba%5D=x",
+		},
+		{
+			// reserved characters within values are escaped
+			struct {
+				V string `url:"v"`
+			}{"a;b/c d=e,f"},
+			";v=a%3Bb%2Fc%20d=e,f",
+		},
+		{map[string]int{"b": 2, "a": 1}, ";a=1;b=2"},
+	}
+
+	for _, tt := range tests {
+		got, err := Matrix(tt.input)
+		if err != nil {
+			t.Errorf("Matrix(%#v) returned error: %v", tt.input, err)
+		}
+		if got != tt.want {
+			t.Errorf("Matrix(%#v) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+
+	if _, err := Matrix("s"); err == nil {
+		t.Errorf("Matrix with invalid input did not return an error")
+	}
+}
+
+// Test the matrix and label serialization examples from the OpenAPI 3
+// specification, see https://spec.openapis.org/oas/v3.1.0#style-examples.
+func TestMatrixParamAndLabel(t *testing.T) {
+	type RGB struct {
+		R int `url:"R"`
+		G int `url:"G"`
+		B int `url:"B"`
+	}
+
+	str := "blue"
+	arr := []string{"blue", "black", "brown"}
+	obj := RGB{100, 200, 150}
+
+	tests := []struct {
+		explode bool
+		matrix  [4]string // empty, string, array, object
+		label   [4]string
+		empty   interface{}
+	}{
+		{
+			false,
+			[4]string{";color", ";color=blue", ";color=blue,black,brown", ";color=R,100,G,200,B,150"},
+			[4]string{".", ".blue", ".blue.black.brown", ".R.100.G.200.B.150"},
+			nil,
+		},
+		{
+			true,
+			[4]string{";color", ";color=blue", ";color=blue;color=black;color=brown", ";R=100;G=200;B=150"},
+			[4]string{".", ".blue", ".blue.black.brown", ".R=100.G=200.B=150"},
+			"",
+		},
+	}
+
+	for _, tt := range tests {
+		for i, input := range []interface{}{tt.empty, str, arr, obj} {
+			got, err := MatrixParam("color", input, tt.explode)
+			if err != nil {
+				t.Errorf("MatrixParam(%#v, %t) returned error: %v", input, tt.explode, err)
+			}
+			if want := tt.matrix[i]; got != want {
+				t.Errorf("MatrixParam(%#v, %t) = %q, want %q", input, tt.explode, got, want)
+			}
+
+			got, err = Label(input, tt.explode)
+			if err != nil {
+				t.Errorf("Label(%#v, %t) returned error: %v", input, tt.explode, err)
+			}
+			if want := tt.label[i]; got != want {
+				t.Errorf("Label(%#v, %t) = %q, want %q", input, tt.explode, got, want)
+			}
+		}
+	}
+
+	// values implementing Encoder are formatted by their EncodeValues methods
+	if got, err := MatrixParam("code", codeValue(7), false); err != nil || got != ";code=C7" {
+		t.Errorf("MatrixParam(codeValue) = %q, %v, want %q", got, err, ";code=C7")
+	}
+	if got, err := Label(colorStruct{255, 0, 0}, false); err != nil || got != ".ff0000" {
+		t.Errorf("Label(colorStruct) = %q, %v, want %q", got, err, ".ff0000")
+	}
+	if _, err := MatrixParam("code", renamedValue("x"), false); err == nil {
+		t.Errorf("MatrixParam(renamedValue) did not return an error")
+	}
+}
+
+func TestMatrixParamAndLabel_Escaping(t *testing.T) {
+	list := []string{"a,b", "c.d", "e;f", "g h"}
+
+	got, _ := MatrixParam("v", list, false)
+	if want := ";v=a%2Cb,c.d,e%3Bf,g%20h"; got != want {
+		t.Errorf("MatrixParam(%q) = %q, want %q", list, got, want)
+	}
+
+	got, _ = Label(list, false)
+	if want := ".a%2Cb.c.d.e;f.g%20h"; got != want {
+		t.Errorf("Label(%q) = %q, want %q", list, got, want)
+	}
+}
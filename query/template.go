@@ -0,0 +1,458 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package query
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// A Template is a URI Template as defined by RFC 6570, supporting all
+// expressions up to and including level 4.
+//
+// Template variables are taken from the fields of a struct, or the entries of
+// a map, passed to Expand.  Each variable name is matched against the URL
+// parameter name of a field, as described in the documentation for the Values
+// function, or failing that against the name in a field's "path" tag (see
+// Path).  Its value is formatted using the field's tag options:
+//
+//   - Values implementing Encoder, other than structs with EncodeValues
+//     methods generated by querygen, are formatted by their EncodeValues
+//     method, passed the variable name as the key.  A single value is a
+//     string value and several are a list value.  Adding any other parameter
+//     is an error.
+//   - Slices and arrays are list values, unless a delimiter option such as
+//     "comma" is given, in which case the joined elements are a string value.
+//   - Structs (other than time.Time) and maps are associative array values,
+//     whose keys and values are the URL parameters they would encode to.
+//   - All other values are string values, formatted as in Values.  This
+//     includes the "int" option for bools and the time.Time options.
+//
+// A variable is undefined if there is no field with its name, if the field is
+// a nil pointer or interface, if it is an empty list or associative array, or
+// if it is empty and its tag specifies the "omitempty" option.  For example:
+//
+//	type Options struct {
+//		Owner  string   `url:"owner"`
+//		Repo   string   `url:"repo"`
+//		State  string   `url:"state,omitempty"`
+//		Labels []string `url:"labels"`
+//	}
+//
+//	t, _ := query.ParseTemplate("/repos/{owner}/{repo}/issues{?state,labels*}")
+//	s, _ := t.Expand(Options{"google", "go-querystring", "", []string{"a", "b"}})
+//	fmt.Print(s) // will output: "/repos/google/go-querystring/issues?labels=a&labels=b"
+type Template struct {
+	raw   string
+	parts []templatePart
+}
+
+// templatePart is a literal string or expression within a Template.
+type templatePart struct {
+	literal string // already percent-encoded
+	op      *templateOp
+	vars    []templateVar
+}
+
+// templateVar is a single variable specifier within an expression.
+type templateVar struct {
+	name    string
+	prefix  int // maximum length of the value, or 0 for no limit
+	explode bool
+}
+
+// templateOp describes how an expression is expanded, following the table in
+// RFC 6570, Appendix A.
+type templateOp struct {
+	first    string // prefix for the first defined variable
+	sep      string // separator between variables
+	named    bool   // whether values are named
+	ifemp    string // follows the name of an empty value
+	reserved bool   // whether reserved characters are allowed unencoded
+}
+
+var templateOps = map[byte]*templateOp{
+	0:   {first: "", sep: ","},
+	'+': {first: "", sep: ",", reserved: true},
+	'.': {first: ".", sep: "."},
+	'/': {first: "/", sep: "/"},
+	';': {first: ";", sep: ";", named: true},
+	'?': {first: "?", sep: "&", named: true, ifemp: "="},
+	'&': {first: "&", sep: "&", named: true, ifemp: "="},
+	'#': {first: "#", sep: ",", reserved: true},
+}
+
+// ParseTemplate parses a URI Template.
+func ParseTemplate(template string) (*Template, error) {
+	t := &Template{raw: template}
+
+	s := template
+	for s != "" {
+		i := strings.IndexByte(s, '{')
+		if i < 0 {
+			i = len(s)
+		}
+		if i > 0 {
+			lit, err := templateLiteral(s[:i])
+			if err != nil {
+				return nil, err
+			}
+			t.parts = append(t.parts, templatePart{literal: lit})
+		}
+		if i == len(s) {
+			break
+		}
+
+		n := strings.IndexByte(s[i:], '}')
+		if n < 0 {
+			return nil, fmt.Errorf("query: unclosed expression in template %q", template)
+		}
+		part, err := parseExpression(s[i+1 : i+n])
+		if err != nil {
+			return nil, err
+		}
+		t.parts = append(t.parts, part)
+		s = s[i+n+1:]
+	}
+
+	return t, nil
+}
+
+// parseExpression parses the contents of a template expression.
+func parseExpression(expr string) (templatePart, error) {
+	var part templatePart
+	if expr == "" {
+		return part, fmt.Errorf("query: empty template expression")
+	}
+
+	part.op = templateOps[0]
+	if op, ok := templateOps[expr[0]]; ok {
+		part.op = op
+		expr = expr[1:]
+	} else if strings.IndexByte("=,!@|", expr[0]) >= 0 {
+		return part, fmt.Errorf("query: reserved operator %q in template expression", expr[0])
+	}
+
+	for _, spec := range strings.Split(expr, ",") {
+		var v templateVar
+		if strings.HasSuffix(spec, "*") {
+			v.explode = true
+			spec = spec[:len(spec)-1]
+		} else if i := strings.IndexByte(spec, ':'); i >= 0 {
+			n := validPrefix(spec[i+1:])
+			if n == 0 {
+				return part, fmt.Errorf("query: invalid prefix modifier in template variable %q", spec)
+			}
+			v.prefix = n
+			spec = spec[:i]
+		}
+		if !validVarname(spec) {
+			return part, fmt.Errorf("query: invalid template variable name %q", spec)
+		}
+		v.name = spec
+		part.vars = append(part.vars, v)
+	}
+
+	return part, nil
+}
+
+// validPrefix returns the maximum length given by a prefix modifier, which
+// must be a number from 1 to 9999, or 0 if s is not valid.
+func validPrefix(s string) int {
+	if s == "" || len(s) > 4 || s[0] == '0' {
+		return 0
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return 0
+		}
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// validVarname reports whether s is a valid RFC 6570 variable name.
+func validVarname(s string) bool {
+	if s == "" || s[0] == '.' || s[len(s)-1] == '.' || strings.Contains(s, "..") {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '_', c == '.':
+		case c == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]):
+			i += 2
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// templateLiteral returns the percent-encoded form of a literal part of a
+// template, or an error if it contains characters not allowed in a literal.
+func templateLiteral(s string) (string, error) {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c <= ' ' || c == 0x7f || strings.IndexByte("\"'<>\\^`{|}", c) >= 0 {
+			return "", fmt.Errorf("query: invalid character %q in template literal", c)
+		}
+	}
+	return templateEscape(s, true), nil
+}
+
+// MustParseTemplate is like ParseTemplate but panics if the template cannot
+// be parsed.
+func MustParseTemplate(template string) *Template {
+	t, err := ParseTemplate(template)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// String returns the unexpanded template.
+func (t *Template) String() string {
+	return t.raw
+}
+
+// Expand returns the result of expanding the template with the variables
+// taken from v, which must be a struct or map as for Values.
+func (t *Template) Expand(v interface{}) (string, error) {
+	return new(Config).Expand(t, v)
+}
+
+// Expand parses and expands the URI Template template with the variables
+// taken from v.
+func Expand(template string, v interface{}) (string, error) {
+	t, err := ParseTemplate(template)
+	if err != nil {
+		return "", err
+	}
+	return t.Expand(v)
+}
+
+// Expand returns the result of expanding the template t with the variables
+// taken from v, using the options in c.
+func (c *Config) Expand(t *Template, v interface{}) (string, error) {
+	lookup, err := c.templateVars(v)
+	if err != nil {
+		return "", err
+	}
+
+	b := new(strings.Builder)
+	for _, part := range t.parts {
+		if part.op == nil {
+			b.WriteString(part.literal)
+			continue
+		}
+		if err := expandExpression(b, part, lookup); err != nil {
+			return "", err
+		}
+	}
+	return b.String(), nil
+}
+
+// expandExpression writes the expansion of a single expression to b,
+// following the algorithm in RFC 6570, Appendix A.
+func expandExpression(b *strings.Builder, part templatePart, lookup func(string) (valueParts, error)) error {
+	op := part.op
+	first := true
+
+	for _, tv := range part.vars {
+		p, err := lookup(tv.name)
+		if err != nil {
+			return err
+		}
+		if p.kind == reflect.Invalid {
+			continue
+		}
+
+		if first {
+			b.WriteString(op.first)
+			first = false
+		} else {
+			b.WriteString(op.sep)
+		}
+
+		if p.kind == reflect.String {
+			s := p.str
+			if tv.prefix > 0 && utf8.RuneCountInString(s) > tv.prefix {
+				i, n := 0, 0
+				for n < tv.prefix {
+					_, size := utf8.DecodeRuneInString(s[i:])
+					i += size
+					n++
+				}
+				s = s[:i]
+			}
+			if op.named {
+				writeTemplateName(b, tv.name, s, op)
+			}
+			b.WriteString(templateEscape(s, op.reserved))
+			continue
+		}
+
+		if tv.prefix > 0 {
+			return fmt.Errorf("query: prefix modifier used with composite template variable %q", tv.name)
+		}
+
+		if !tv.explode {
+			if op.named {
+				b.WriteString(tv.name)
+				b.WriteByte('=')
+			}
+			list := p.list
+			if p.kind == reflect.Map {
+				list = p.flatten()
+			}
+			for i, s := range list {
+				if i > 0 {
+					b.WriteByte(',')
+				}
+				b.WriteString(templateEscape(s, op.reserved))
+			}
+			continue
+		}
+
+		if p.kind == reflect.Slice {
+			for i, s := range p.list {
+				if i > 0 {
+					b.WriteString(op.sep)
+				}
+				if op.named {
+					writeTemplateName(b, tv.name, s, op)
+				}
+				b.WriteString(templateEscape(s, op.reserved))
+			}
+			continue
+		}
+
+		for i, kv := range p.pairs {
+			if i > 0 {
+				b.WriteString(op.sep)
+			}
+			key := templateEscape(kv.key, op.reserved)
+			if op.named {
+				writeTemplateName(b, key, kv.value, op)
+			} else {
+				b.WriteString(key)
+				b.WriteByte('=')
+			}
+			b.WriteString(templateEscape(kv.value, op.reserved))
+		}
+	}
+
+	return nil
+}
+
+// writeTemplateName writes the name of a named value to b, followed by "=" or
+// the operator's ifemp string if value is empty.
+func writeTemplateName(b *strings.Builder, name, value string, op *templateOp) {
+	b.WriteString(name)
+	if value == "" {
+		b.WriteString(op.ifemp)
+	} else {
+		b.WriteByte('=')
+	}
+}
+
+// templateVars returns a function that looks up template variables in v.
+func (c *Config) templateVars(v interface{}) (func(string) (valueParts, error), error) {
+	undefined := valueParts{kind: reflect.Invalid}
+
+	val := reflect.ValueOf(v)
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			val = reflect.Value{}
+			break
+		}
+		val = val.Elem()
+	}
+
+	switch val.Kind() {
+	case reflect.Invalid:
+		return func(string) (valueParts, error) { return undefined, nil }, nil
+
+	case reflect.Struct:
+		fields := cachedTypeFields(val.Type(), "url")
+		if c.DisallowConflicts && len(fields.conflicts) > 0 {
+			return nil, fmt.Errorf("query: conflicting fields for parameter %q in %v", fields.conflicts[0], val.Type())
+		}
+		if c.Strict && fields.tagErr != nil {
+			return nil, fields.tagErr
+		}
+		pathFields := cachedTypeFields(val.Type(), "path")
+		return func(name string) (valueParts, error) {
+			f, ok := findField(fields.list, name)
+			if !ok {
+				f, ok = findField(pathFields.list, name)
+			}
+			if !ok {
+				return undefined, nil
+			}
+			sv, ok := fieldByIndex(val, f.index)
+			if !ok || f.opts.Contains("omitempty") && isEmptyValue(sv) {
+				return undefined, nil
+			}
+			return c.templateValue(sv, name, f.opts, f.sf)
+		}, nil
+
+	case reflect.Map:
+		if err := checkMapKeys(val); err != nil {
+			return nil, err
+		}
+		return func(name string) (valueParts, error) {
+			iter := val.MapRange()
+			for iter.Next() {
+				k, err := mapKeyString(iter.Key())
+				if err != nil {
+					return undefined, err
+				}
+				if k == name {
+					return c.templateValue(iter.Value(), name, nil, reflect.StructField{})
+				}
+			}
+			return undefined, nil
+		}, nil
+	}
+
+	return nil, fmt.Errorf("query: Expand() expects struct or map input. Got %v", val.Kind())
+}
+
+// templateValue returns the parts of a template variable's value, treating
+// empty lists and associative arrays as undefined.
+func (c *Config) templateValue(v reflect.Value, name string, opts tagOptions, sf reflect.StructField) (valueParts, error) {
+	p, err := c.valueParts(v, name, opts, sf)
+	if err != nil {
+		return p, err
+	}
+	if p.kind == reflect.Slice && len(p.list) == 0 || p.kind == reflect.Map && len(p.pairs) == 0 {
+		p.kind = reflect.Invalid
+	}
+	return p, nil
+}
+
+// templateEscape percent-encodes s for use in an expanded template.  All bytes
+// other than the RFC 3986 unreserved characters are escaped, unless reserved
+// is true, in which case reserved characters and existing percent-encoded
+// triplets are also left as is.
+func templateEscape(s string, reserved bool) string {
+	if !reserved {
+		return escapeRFC3986(s)
+	}
+	return percentEscape(s, func(s string, i int) bool {
+		c := s[i]
+		return isUnreserved(c) || strings.IndexByte(":/?#[]@!$&'()*+,;=", c) >= 0 ||
+			c == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2])
+	})
+}
+
+// isHex reports whether c is a hexadecimal digit.
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
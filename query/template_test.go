@@ -0,0 +1,361 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package query
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// codeValue is a non-struct type implementing Encoder.
+type codeValue int
+
+func (c codeValue) EncodeValues(key string, v *url.Values) error {
+	v.Set(key, fmt.Sprintf("C%d", int(c)))
+	return nil
+}
+
+// renamedValue implements Encoder by adding a parameter other than key.
+type renamedValue string
+
+func (r renamedValue) EncodeValues(key string, v *url.Values) error {
+	v.Set("x_"+key, string(r))
+	return nil
+}
+
+// rfc6570Vars holds the example variables from RFC 6570, Section 3.2.
+type rfc6570Vars struct {
+	Count     []string          `url:"count"`
+	Dom       []string          `url:"dom"`
+	Dub       string            `url:"dub"`
+	Hello     string            `url:"hello"`
+	Half      string            `url:"half"`
+	Var       string            `url:"var"`
+	Who       string            `url:"who"`
+	Base      string            `url:"base"`
+	Path      string            `url:"path"`
+	List      []string          `url:"list"`
+	Keys      rfc6570Keys       `url:"keys"`
+	V         string            `url:"v"`
+	X         string            `url:"x"`
+	Y         string            `url:"y"`
+	Empty     string            `url:"empty"`
+	EmptyKeys map[string]string `url:"empty_keys"`
+	Undef     *string           `url:"undef"`
+}
+
+type rfc6570Keys struct {
+	Semi  string `url:"semi"`
+	Dot   string `url:"dot"`
+	Comma string `url:"comma"`
+}
+
+var rfc6570Values = rfc6570Vars{
+	Count:     []string{"one", "two", "three"},
+	Dom:       []string{"example", "com"},
+	Dub:       "me/too",
+	Hello:     "Hello World!",
+	Half:      "50%",
+	Var:       "value",
+	Who:       "fred",
+	Base:      "http://example.com/home/",
+	Path:      "/foo/bar",
+	List:      []string{"red", "green", "blue"},
+	Keys:      rfc6570Keys{";", ".", ","},
+	V:         "6",
+	X:         "1024",
+	Y:         "768",
+	Empty:     "",
+	EmptyKeys: map[string]string{},
+}
+
+// Test the examples from RFC 6570, Section 3.2.
+func TestTemplate_RFC6570(t *testing.T) {
+	tests := []struct {
+		template string
+		want     string
+	}{
+		// Section 3.2.1: Variable Expansion
+		{"{count}", "one,two,three"},
+		{"{count*}", "one,two,three"},
+		{"{/count}", "/one,two,three"},
+		{"{/count*}", "/one/two/three"},
+		{"{;count}", ";count=one,two,three"},
+		{"{;count*}", ";count=one;count=two;count=three"},
+		{"{?count}", "?count=one,two,three"},
+		{"{?count*}", "?count=one&count=two&count=three"},
+		{"{&count*}", "&count=one&count=two&count=three"},
+
+		// Section 3.2.2: Simple String Expansion
+		{"{var}", "value"},
+		{"{hello}", "Hello%20World%21"},
+		{"{half}", "50%25"},
+		{"O{empty}X", "OX"},
+		{"O{undef}X", "OX"},
+		{"{x,y}", "1024,768"},
+		{"{x,hello,y}", "1024,Hello%20World%21,768"},
+		{"?{x,empty}", "?1024,"},
+		{"?{x,undef}", "?1024"},
+		{"?{undef,y}", "?768"},
+		{"{var:3}", "val"},
+		{"{var:30}", "value"},
+		{"{list}", "red,green,blue"},
+		{"{list*}", "red,green,blue"},
+		{"{keys}", "semi,%3B,dot,.,comma,%2C"},
+		{"{keys*}", "semi=%3B,dot=.,comma=%2C"},
+
+		// Section 3.2.3: Reserved Expansion
+		{"{+var}", "value"},
+		{"{+hello}", "Hello%20World!"},
+		{"{+half}", "50%25"},
+		{"{base}index", "http%3A%2F%2Fexample.com%2Fhome%2Findex"},
+		{"{+base}index", "http://example.com/home/index"},
+		{"O{+empty}X", "OX"},
+		{"O{+undef}X", "OX"},
+		{"{+path}/here", "/foo/bar/here"},
+		{"here?ref={+path}", "here?ref=/foo/bar"},
+		{"up{+path}{var}/here", "up/foo/barvalue/here"},
+		{"{+x,hello,y}", "1024,Hello%20World!,768"},
+		{"{+path,x}/here", "/foo/bar,1024/here"},
+		{"{+path:6}/here", "/foo/b/here"},
+		{"{+list}", "red,green,blue"},
+		{"{+list*}", "red,green,blue"},
+		{"{+keys}", "semi,;,dot,.,comma,,"},
+		{"{+keys*}", "semi=;,dot=.,comma=,"},
+
+		// Section 3.2.4: Fragment Expansion
+		{"{#var}", "#value"},
+		{"{#hello}", "#Hello%20World!"},
+		{"{#half}", "#50%25"},
+		{"foo{#empty}", "foo#"},
+		{"foo{#undef}", "foo"},
+		{"{#x,hello,y}", "#1024,Hello%20World!,768"},
+		{"{#path,x}/here", "#/foo/bar,1024/here"},
+		{"{#path:6}/here", "#/foo/b/here"},
+		{"{#list}", "#red,green,blue"},
+		{"{#list*}", "#red,green,blue"},
+		{"{#keys}", "#semi,;,dot,.,comma,,"},
+		{"{#keys*}", "#semi=;,dot=.,comma=,"},
+
+		// Section 3.2.5: Label Expansion with Dot-Prefix
+		{"{.who}", ".fred"},
+		{"{.who,who}", ".fred.fred"},
+		{"{.half,who}", ".50%25.fred"},
+		{"www{.dom*}", "www.example.com"},
+		{"X{.var}", "X.value"},
+		{"X{.empty}", "X."},
+		{"X{.undef}", "X"},
+		{"X{.var:3}", "X.val"},
+		{"X{.list}", "X.red,green,blue"},
+		{"X{.list*}", "X.red.green.blue"},
+		{"X{.keys}", "X.semi,%3B,dot,.,comma,%2C"},
+		{"X{.keys*}", "X.semi=%3B.dot=..comma=%2C"},
+		{"X{.empty_keys}", "X"},
+		{"X{.empty_keys*}", "X"},
+
+		// Section 3.2.6: Path Segment Expansion
+		{"{/who}", "/fred"},
+		{"{/who,who}", "/fred/fred"},
+		{"{/half,who}", "/50%25/fred"},
+		{"{/who,dub}", "/fred/me%2Ftoo"},
+		{"{/var}", "/value"},
+		{"{/var,empty}", "/value/"},
+		{"{/var,undef}", "/value"},
+		{"{/var,x}/here", "/value/1024/here"},
+		{"{/var:1,var}", "/v/value"},
+		{"{/list}", "/red,green,blue"},
+		{"{/list*}", "/red/green/blue"},
+		{"{/list*,path:4}", "/red/green/blue/%2Ffoo"},
+		{"{/keys}", "/semi,%3B,dot,.,comma,%2C"},
+		{"{/keys*}", "/semi=%3B/dot=./comma=%2C"},
+
+		// Section 3.2.7: Path-Style Parameter Expansion
+		{"{;who}", ";who=fred"},
+		{"{;half}", ";half=50%25"},
+		{"{;empty}", ";empty"},
+		{"{;v,empty,who}", ";v=6;empty;who=fred"},
+		{"{;v,bar,who}", ";v=6;who=fred"},
+		{"{;x,y}", ";x=1024;y=768"},
+		{"{;x,y,empty}", ";x=1024;y=768;empty"},
+		{"{;x,y,undef}", ";x=1024;y=768"},
+		{"{;hello:5}", ";hello=Hello"},
+		{"{;list}", ";list=red,green,blue"},
+		{"{;list*}", ";list=red;list=green;list=blue"},
+		{"{;keys}", ";keys=semi,%3B,dot,.,comma,%2C"},
+		{"{;keys*}", ";semi=%3B;dot=.;comma=%2C"},
+
+		// Section 3.2.8: Form-Style Query Expansion
+		{"{?who}", "?who=fred"},
+		{"{?half}", "?half=50%25"},
+		{"{?x,y}", "?x=1024&y=768"},
+		{"{?x,y,empty}", "?x=1024&y=768&empty="},
+		{"{?x,y,undef}", "?x=1024&y=768"},
+		{"{?var:3}", "?var=val"},
+		{"{?list}", "?list=red,green,blue"},
+		{"{?list*}", "?list=red&list=green&list=blue"},
+		{"{?keys}", "?keys=semi,%3B,dot,.,comma,%2C"},
+		{"{?keys*}", "?semi=%3B&dot=.&comma=%2C"},
+
+		// Section 3.2.9: Form-Style Query Continuation
+		{"{&who}", "&who=fred"},
+		{"{&half}", "&half=50%25"},
+		{"?fixed=yes{&x}", "?fixed=yes&x=1024"},
+		{"{&x,y,empty}", "&x=1024&y=768&empty="},
+		{"{&var:3}", "&var=val"},
+		{"{&list}", "&list=red,green,blue"},
+		{"{&list*}", "&list=red&list=green&list=blue"},
+		{"{&keys}", "&keys=semi,%3B,dot,.,comma,%2C"},
+		{"{&keys*}", "&semi=%3B&dot=.&comma=%2C"},
+	}
+
+	for _, tt := range tests {
+		got, err := Expand(tt.template, rfc6570Values)
+		if err != nil {
+			t.Errorf("Expand(%q) returned error: %v", tt.template, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Expand(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestTemplate_Formatting(t *testing.T) {
+	type Options struct {
+		Owner   string    `url:"owner"`
+		Repo    string    `url:"repo"`
+		State   string    `url:"state,omitempty"`
+		Labels  []string  `url:"labels"`
+		Fields  []string  `url:"fields,comma"`
+		IDs     []int     `url:"ids" del:"|"`
+		Since   time.Time `url:"since,unix"`
+		Day     time.Time `url:"day" layout:"2006-01-02"`
+		Draft   bool      `url:"draft,int"`
+		Unicode string    `url:"unicode"`
+		Color   colorStruct
+		Code    codeValue           `url:"code"`
+		Codes   *codeValue          `url:"codes"`
+		Custom  customEncodedStruct `url:"custom"`
+	}
+
+	opts := Options{
+		Owner:   "google",
+		Repo:    "go-querystring",
+		Labels:  []string{"bug", "help wanted"},
+		Fields:  []string{"a", "b"},
+		IDs:     []int{1, 2},
+		Since:   time.Date(2000, 1, 1, 12, 34, 56, 0, time.UTC),
+		Day:     time.Date(2000, 1, 1, 12, 34, 56, 0, time.UTC),
+		Unicode: "ü€x",
+		Color:   colorStruct{255, 0, 0},
+		Code:    7,
+		Custom:  customEncodedStruct{"x", 1},
+	}
+
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"/repos/{owner}/{repo}/issues{?state,labels*}", "/repos/google/go-querystring/issues?labels=bug&labels=help%20wanted"},
+		{"{?fields,fields*}", "?fields=a%2Cb&fields=a%2Cb"},
+		{"{+fields}", "a,b"},
+		{"{ids}", "1%7C2"},
+		{"{?since,day,draft}", "?since=946730096&day=2000-01-01&draft=0"},
+		{"{unicode:2}", "%C3%BC%E2%82%AC"},
+
+		// values are formatted by their EncodeValues methods, unless
+		// generated
+		{"{?Color,code,codes}", "?Color=ff0000&code=C7&codes=C0"},
+		{"{?custom*}", "?A=x&b=1"},
+		{"{missing}{?missing}", ""},
+		{"/path/ü", "/path/%C3%BC"},
+		{"/path/%7E", "/path/%7E"},
+	}
+
+	for _, tt := range tests {
+		got, err := Expand(tt.template, opts)
+		if err != nil {
+			t.Errorf("Expand(%q) returned error: %v", tt.template, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Expand(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestTemplate_Inputs(t *testing.T) {
+	tmpl := MustParseTemplate("{a}{?b*}")
+	if got, want := tmpl.String(), "{a}{?b*}"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	tests := []struct {
+		input interface{}
+		want  string
+	}{
+		{nil, ""},
+		{(*struct{})(nil), ""},
+		{map[string]interface{}{"a": "x", "b": []int{1, 2}}, "x?b=1&b=2"},
+		{&struct {
+			A string `url:"a"`
+		}{"x"}, "x"},
+	}
+
+	for _, tt := range tests {
+		got, err := tmpl.Expand(tt.input)
+		if err != nil {
+			t.Errorf("Expand(%#v) returned error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Expand(%#v) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestTemplate_Errors(t *testing.T) {
+	parseErrors := []string{
+		"{",
+		"{}",
+		"{var",
+		"}{var}",
+		"{=var}",
+		"{|var}",
+		"{var:0}",
+		"{var:10000}",
+		"{var:+1}",
+		"{var:}",
+		"{v-ar}",
+		"{.var.}",
+		"{a..b}",
+		"{var,}",
+		"a b",
+		"a<b>",
+	}
+	for _, tmpl := range parseErrors {
+		if _, err := ParseTemplate(tmpl); err == nil {
+			t.Errorf("ParseTemplate(%q) did not return an error", tmpl)
+		}
+	}
+
+	expandErrors := []struct {
+		template string
+		input    interface{}
+	}{
+		{"{list:3}", rfc6570Values},
+		{"{keys:3}", rfc6570Values},
+		{"{a}", "string"},
+		{"{a}", map[int]string{1: "a"}},
+		{"{a}", map[string]renamedValue{"a": "x"}},
+	}
+	for _, tt := range expandErrors {
+		if _, err := Expand(tt.template, tt.input); err == nil {
+			t.Errorf("Expand(%q, %#v) did not return an error", tt.template, tt.input)
+		}
+	}
+}